@@ -1,19 +1,10 @@
 package Services
 
 import (
-	"math/rand"
 	"sync"
 	"time"
 )
 
-// Variables
-
-// Arrivals
-var arrivals = make(chan *Car, 20)
-var StaggerMax = 2
-var StaggerMin = 1
-var CarNum = 100
-
 // Initializations
 
 type FuelType string
@@ -26,6 +17,24 @@ const (
 	Electric = "electric"
 )
 
+type CarClass string
+
+// Constants for car classes
+const (
+	Emergency CarClass = "emergency"
+	Fleet     CarClass = "fleet"
+	Regular   CarClass = "regular"
+)
+
+// PriorityClasses lists the car classes served ahead of regular cars in their choice order
+var PriorityClasses = []CarClass{Emergency, Fleet}
+
+// ClassSetup describes the cars of a priority class
+type ClassSetup struct {
+	Share    float64 // share of arriving cars in the class
+	Priority int     // cars with a higher priority are served first, regular cars have 0
+}
+
 // Car represents a car arriving at the gas station
 type Car struct {
 	ID                 int
@@ -37,19 +46,111 @@ type Car struct {
 	FuelTime           time.Duration
 	PayTime            time.Duration
 	TotalTime          time.Duration
+	ArrivalTime        time.Duration
+	ShopBasket         float64
+	Volume             float64
+	Price              float64 // price per unit quoted on arrival, zero without a pricing strategy
+	RegisterClass      RegisterClass
+	Class              CarClass
+	Priority           int
+	PaymentMethod      PaymentMethod // empty without payment methods
+	PaymentAttempts    int
+	Stand              int           // id of the stand the car fueled at
+	BlockedTime        time.Duration // time spent fueled but blocked by the cars ahead in its lane
+	payAttempts        []time.Duration
+	fuelDraw           float64 // uniform draw for fueling times of dispensers with their own times
+	fuelTimeDrawn      bool
+	fuelTimeRecorded   bool // fueling time comes from a trace, even when zero
+	payTimeRecorded    bool // payment time comes from a trace, even when zero
+	Wash               bool // car buys a wash
+	WashTime           time.Duration
+	WashQueueTime      time.Duration
+	washQueueEnter     time.Time
+	stage              int  // index of the current stage in the pipeline
+	heldStand          bool // car keeps its stand until it has paid
 	carSync            *sync.WaitGroup
 }
 
 // Routines
 
 // CreateCarsRoutine creates cars that arrive at the station
-func CreateCarsRoutine() {
-	for i := 0; i < CarNum; i++ {
+func (st *Station) CreateCarsRoutine() {
+	// Replaying recorded arrivals instead of generating them
+	if st.Trace != nil {
+		st.replayTraceRoutine()
+		return
+	}
+	for i := 0; i < st.CarNum; i++ {
 		// Adds a new car to station queue
-		arrivals <- &Car{ID: i, Fuel: genFuelType(), carSync: &sync.WaitGroup{}, StandQueueEnter: time.Now()}
+		st.arrive(&Car{ID: i, Fuel: st.genFuelType()})
 		// Staggers car creation
-		stagger := time.Duration(rand.Intn(StaggerMax-StaggerMin) + StaggerMin)
-		time.Sleep(stagger * time.Millisecond)
+		doSleeping(randomTime(st.rng.arrival, st.StaggerMin, st.StaggerMax))
+	}
+	st.arrivals.Close()
+}
+
+// Utilities
+
+// arrive sends a new car into the station entrance queue
+//
+// Service times not known in advance are drawn here so they do not depend on
+// the order in which stands and registers get to serve the cars.
+func (st *Station) arrive(car *Car) {
+	if !car.fuelTimeRecorded {
+		setup := st.Fuels[car.Fuel]
+		car.FuelTime = randomTime(st.rng.fuelTime, setup.MinT, setup.MaxT)
+		car.fuelDraw = st.rng.dispenserTime.Float64()
+		car.fuelTimeDrawn = true
+	}
+	if setup := st.Fuels[car.Fuel]; car.Volume == 0 && setup.MaxVolume > 0 {
+		car.Volume = randomAmount(st.rng.volume, setup.MinVolume, setup.MaxVolume)
+	}
+	// Recorded cars keep their recorded baskets
+	if st.Trace == nil && st.MaxShopBasket > 0 {
+		car.ShopBasket = randomAmount(st.rng.basket, st.MinShopBasket, st.MaxShopBasket)
+	}
+	st.drawWash(car)
+	car.Class = st.chooseCarClass()
+	car.Priority = st.Classes[car.Class].Priority
+	car.PaymentMethod = st.choosePaymentMethod()
+	car.RegisterClass = st.chooseRegisterClass(car)
+	if !car.payTimeRecorded {
+		st.drawPayment(car)
+	} else {
+		car.PaymentAttempts = 1
+	}
+	car.carSync = &sync.WaitGroup{}
+	car.StandQueueEnter = time.Now()
+	car.ArrivalTime = time.Duration(st.Elapsed())
+	st.Arrived.Add(1)
+	st.fuelCounts[car.Fuel].arrived.Add(1)
+	st.logEvent(car, EventArrival, -1)
+	cars := st.quote(car)
+	if cars == 0 {
+		st.fuelCounts[car.Fuel].balked.Add(1)
+		st.logEvent(car, EventBalk, -1)
+		return
+	}
+	st.arrivals.Push(car)
+	// Cars drawn in by a lower price get ids past the planned ones and draw in no further cars
+	if cars == 2 && car.ID < st.TotalCars() {
+		id := st.TotalCars() + int(st.extraCars.Add(1)) - 1
+		st.arrive(&Car{ID: id, Fuel: car.Fuel})
+	}
+}
+
+// chooseCarClass picks the class of an arriving car by the class shares
+func (st *Station) chooseCarClass() CarClass {
+	if len(st.Classes) == 0 {
+		return Regular
+	}
+	draw := st.rng.carClass.Float64()
+	cumulative := 0.0
+	for _, class := range PriorityClasses {
+		cumulative += st.Classes[class].Share
+		if draw < cumulative {
+			return class
+		}
 	}
-	close(arrivals)
+	return Regular
 }
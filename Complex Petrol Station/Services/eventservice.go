@@ -0,0 +1,90 @@
+package Services
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"log"
+	"strconv"
+)
+
+// Initializations
+
+type EventKind string
+
+// Constants for car lifecycle events
+const (
+	EventArrival          EventKind = "arrival"
+	EventStandAssigned    EventKind = "stand_assigned"
+	EventFuelStart        EventKind = "fuel_start"
+	EventFuelEnd          EventKind = "fuel_end"
+	EventBlocked          EventKind = "blocked"
+	EventBuildingQueue    EventKind = "building_queue"
+	EventRegisterAssigned EventKind = "register_assigned"
+	EventPaymentStart     EventKind = "payment_start"
+	EventPaymentDeclined  EventKind = "payment_declined"
+	EventPaymentEnd       EventKind = "payment_end"
+	EventWashQueue        EventKind = "wash_queue"
+	EventWashStart        EventKind = "wash_start"
+	EventWashEnd          EventKind = "wash_end"
+	EventExit             EventKind = "exit"
+	EventBalk             EventKind = "balk"
+)
+
+// Event represents a single lifecycle transition of a car
+type Event struct {
+	Time     int64     `json:"time"`     // simulated milliseconds since start
+	Car      int       `json:"car"`      // car id
+	Fuel     FuelType  `json:"fuel"`     // fuel type of the car
+	Kind     EventKind `json:"event"`    // lifecycle transition
+	Location int       `json:"location"` // stand or register id, -1 outside of them
+}
+
+// Routines
+
+// EventLogRoutine writes events as json lines or csv until the channel is closed
+func EventLogRoutine(events <-chan Event, w io.Writer, format string, done func()) {
+	defer done()
+	var writeEvent func(Event) error
+	switch format {
+	case "csv":
+		writer := csv.NewWriter(w)
+		defer writer.Flush()
+		writer.Write([]string{"time", "car", "fuel", "event", "location"})
+		writeEvent = func(e Event) error {
+			return writer.Write([]string{
+				strconv.FormatInt(e.Time, 10),
+				strconv.Itoa(e.Car),
+				string(e.Fuel),
+				string(e.Kind),
+				strconv.Itoa(e.Location),
+			})
+		}
+	default:
+		encoder := json.NewEncoder(w)
+		writeEvent = func(e Event) error {
+			return encoder.Encode(e)
+		}
+	}
+	for event := range events {
+		if err := writeEvent(event); err != nil {
+			log.Printf("Error writing event log: %v", err)
+		}
+	}
+}
+
+// Utilities
+
+// logEvent records a lifecycle event of a car if the event log is enabled
+func (st *Station) logEvent(car *Car, kind EventKind, location int) {
+	if st.Events == nil {
+		return
+	}
+	st.Events <- Event{
+		Time:     st.Elapsed(),
+		Car:      car.ID,
+		Fuel:     car.Fuel,
+		Kind:     kind,
+		Location: location,
+	}
+}
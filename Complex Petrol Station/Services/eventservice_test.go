@@ -0,0 +1,87 @@
+package Services
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestEventLogRoutineFormats(t *testing.T) {
+	events := []Event{
+		{Time: 3, Car: 1, Fuel: Gas, Kind: EventArrival, Location: -1},
+		{Time: 7, Car: 1, Fuel: Gas, Kind: EventFuelStart, Location: 2},
+	}
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{"csv", "time,car,fuel,event,location\n3,1,gas,arrival,-1\n7,1,gas,fuel_start,2\n"},
+		{"jsonl", `{"time":3,"car":1,"fuel":"gas","event":"arrival","location":-1}` + "\n" +
+			`{"time":7,"car":1,"fuel":"gas","event":"fuel_start","location":2}` + "\n"},
+	}
+	for _, tt := range tests {
+		var b bytes.Buffer
+		ch := make(chan Event, len(events))
+		for _, e := range events {
+			ch <- e
+		}
+		close(ch)
+		var wg sync.WaitGroup
+		wg.Add(1)
+		EventLogRoutine(ch, &b, tt.format, wg.Done)
+		wg.Wait()
+		if b.String() != tt.want {
+			t.Errorf("%s log = %q, want %q", tt.format, b.String(), tt.want)
+		}
+	}
+}
+
+func TestEventLifecycleOrder(t *testing.T) {
+	st := NewStation()
+	st.Seed = 1
+	st.CarNum = 20
+	st.Log = io.Discard
+	st.Events = make(chan Event, 100)
+	var b bytes.Buffer
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go EventLogRoutine(st.Events, &b, "jsonl", wg.Done)
+	st.Open()
+	for range st.Exit {
+	}
+	wg.Wait()
+
+	order := []EventKind{EventArrival, EventStandAssigned, EventFuelStart, EventFuelEnd, EventBuildingQueue,
+		EventRegisterAssigned, EventPaymentStart, EventPaymentEnd, EventExit}
+	cars := make(map[int][]Event)
+	scanner := bufio.NewScanner(strings.NewReader(b.String()))
+	for scanner.Scan() {
+		var e Event
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			t.Fatalf("decode %s: %v", scanner.Text(), err)
+		}
+		cars[e.Car] = append(cars[e.Car], e)
+	}
+	if len(cars) != st.CarNum {
+		t.Fatalf("got events of %d cars, want %d", len(cars), st.CarNum)
+	}
+	for id, events := range cars {
+		if len(events) != len(order) {
+			t.Errorf("car %d has %d events, want %d", id, len(events), len(order))
+			continue
+		}
+		for i, e := range events {
+			if e.Kind != order[i] {
+				t.Errorf("car %d event %d is %s, want %s", id, i, e.Kind, order[i])
+				break
+			}
+			if i > 0 && e.Time < events[i-1].Time {
+				t.Errorf("car %d %s at %d comes before %s at %d", id, e.Kind, e.Time, events[i-1].Kind, events[i-1].Time)
+			}
+		}
+	}
+}
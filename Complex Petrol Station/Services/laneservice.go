@@ -0,0 +1,157 @@
+package Services
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Initializations
+
+// IslandSetup describes a pump island with a lane of positions on each side
+type IslandSetup struct {
+	Fuel      FuelType
+	Sides     int // lanes along the island
+	Positions int // pumps per lane, one behind the other
+}
+
+// Lane is one side of a pump island where cars fuel one behind the other
+//
+// Cars enter from the back and drive to the frontmost position they can reach,
+// a fueled car leaves forward once every position ahead of it is empty.
+type Lane struct {
+	Id        int
+	Island    int
+	Side      int
+	Type      FuelType
+	Positions []*FuelStand // front position first
+	Queue     *CarQueue
+	mu        sync.Mutex
+	moved     *sync.Cond // signalled whenever a car leaves its position
+	cars      []*Car     // car at every position, nil when empty
+	blocked   int
+}
+
+// LaneState describes the queue and positions of a single lane
+type LaneState struct {
+	Id       int      `json:"id"`
+	Island   int      `json:"island"`
+	Side     int      `json:"side"`
+	Fuel     FuelType `json:"fuel"`
+	Queue    int      `json:"queue"`
+	Occupied int      `json:"occupied"` // positions holding a car
+	Blocked  int      `json:"blocked"`  // fueled cars waiting for the cars ahead to leave
+}
+
+// NewLane creates a lane with its positions, the positions take the stand ids from firstStand on
+func NewLane(id, island, side int, fuel FuelType, positions, firstStand, bufferSize int) *Lane {
+	lane := &Lane{
+		Id:     id,
+		Island: island,
+		Side:   side,
+		Type:   fuel,
+		Queue:  NewCarQueue(bufferSize),
+		cars:   make([]*Car, positions),
+	}
+	lane.moved = sync.NewCond(&lane.mu)
+	for i := 0; i < positions; i++ {
+		lane.Positions = append(lane.Positions, NewFuelStand(firstStand+i, fuel, bufferSize))
+	}
+	return lane
+}
+
+// Routines
+
+// LaneRoutine lets the queued cars of a lane into its positions
+func (st *Station) LaneRoutine(lane *Lane) {
+	defer st.standWaiter.Done()
+	fmt.Fprintf(st.Log, "Lane %d is open\n", lane.Id)
+	var positions sync.WaitGroup
+	for car, ok := lane.Queue.Pop(); ok; car, ok = lane.Queue.Pop() {
+		position := lane.enter(car)
+		positions.Add(1)
+		go func() {
+			defer positions.Done()
+			st.positionRoutine(lane, position, car)
+		}()
+	}
+	positions.Wait()
+	fmt.Fprintf(st.Log, "Lane %d is closed\n", lane.Id)
+}
+
+// positionRoutine serves a car at its position and lets it leave once the way ahead is clear
+func (st *Station) positionRoutine(lane *Lane, position int, car *Car) {
+	fs := lane.Positions[position]
+	st.serveAtStand(fs, car)
+	// Blocked until the cars ahead have left
+	lane.mu.Lock()
+	if lane.blockedAhead(position) {
+		st.logEvent(car, EventBlocked, fs.Id)
+		blockedSince := time.Now()
+		lane.blocked++
+		for lane.blockedAhead(position) {
+			lane.moved.Wait()
+		}
+		lane.blocked--
+		car.BlockedTime = time.Duration(time.Since(blockedSince).Milliseconds())
+	}
+	lane.cars[position] = nil
+	lane.moved.Broadcast()
+	lane.mu.Unlock()
+	fs.release()
+}
+
+// Utilities
+
+// enter waits until the car can drive in from the back and returns the position it takes
+func (lane *Lane) enter(car *Car) int {
+	lane.mu.Lock()
+	defer lane.mu.Unlock()
+	for lane.cars[len(lane.cars)-1] != nil {
+		lane.moved.Wait()
+	}
+	// Driving forward up to the last occupied position
+	position := len(lane.cars) - 1
+	for position > 0 && lane.cars[position-1] == nil {
+		position--
+	}
+	lane.cars[position] = car
+	return position
+}
+
+// blockedAhead reports whether a car occupies a position ahead of the given one, the lane must be locked
+func (lane *Lane) blockedAhead(position int) bool {
+	for _, car := range lane.cars[:position] {
+		if car != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// load returns the cars queued for and occupying the lane
+func (lane *Lane) load() int {
+	lane.mu.Lock()
+	defer lane.mu.Unlock()
+	occupied := 0
+	for _, car := range lane.cars {
+		if car != nil {
+			occupied++
+		}
+	}
+	return lane.Queue.Len() + occupied
+}
+
+// state returns the current state of the lane
+func (lane *Lane) state() LaneState {
+	lane.mu.Lock()
+	defer lane.mu.Unlock()
+	state := LaneState{Id: lane.Id, Island: lane.Island, Side: lane.Side, Fuel: lane.Type,
+		Queue: lane.Queue.Len(), Blocked: lane.blocked}
+	for _, car := range lane.cars {
+		if car != nil {
+			state.Occupied++
+		}
+	}
+	return state
+}
@@ -0,0 +1,91 @@
+package Services
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNewLane(t *testing.T) {
+	lane := NewLane(3, 1, 2, Diesel, 3, 7, 4)
+	if len(lane.Positions) != 3 || lane.Positions[0].Id != 7 || lane.Positions[2].Id != 9 || lane.Positions[1].Type != Diesel {
+		t.Errorf("positions of a lane from stand 7 = %+v", lane.Positions)
+	}
+	if state := lane.state(); state != (LaneState{Id: 3, Island: 1, Side: 2, Fuel: Diesel}) {
+		t.Errorf("state of an empty lane = %+v", state)
+	}
+}
+
+func TestLaneEnter(t *testing.T) {
+	tests := []struct {
+		name     string
+		occupied []bool // front position first
+		want     int
+	}{
+		{"empty lane", []bool{false, false, false}, 0},
+		{"front taken", []bool{true, false, false}, 1},
+		{"cannot pass a car", []bool{false, true, false}, 2},
+		{"stops behind a car", []bool{false, true, false, false}, 2},
+		{"single position", []bool{false}, 0},
+	}
+	for _, tt := range tests {
+		lane := NewLane(0, 0, 0, Gas, len(tt.occupied), 0, 1)
+		for i, occupied := range tt.occupied {
+			if occupied {
+				lane.cars[i] = &Car{ID: 100 + i}
+			}
+		}
+		if got := lane.enter(&Car{ID: 1}); got != tt.want {
+			t.Errorf("%s: entered position %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestLaneEnterWaitsForBack(t *testing.T) {
+	lane := NewLane(0, 0, 0, Gas, 2, 0, 1)
+	lane.cars[1] = &Car{ID: 2}
+	entered := make(chan int)
+	go func() { entered <- lane.enter(&Car{ID: 3}) }()
+	select {
+	case <-entered:
+		t.Fatal("car entered a lane whose back position is taken")
+	case <-time.After(20 * time.Millisecond):
+	}
+	lane.mu.Lock()
+	lane.cars[1] = nil
+	lane.moved.Broadcast()
+	lane.mu.Unlock()
+	select {
+	case position := <-entered:
+		if position != 0 {
+			t.Errorf("entered position %d, want the front", position)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("car did not enter after the back position was freed")
+	}
+}
+
+func TestLaneBlockedAhead(t *testing.T) {
+	lane := NewLane(0, 0, 0, Gas, 3, 0, 2)
+	lane.cars[1] = &Car{ID: 1}
+	lane.cars[2] = &Car{ID: 2}
+	lane.Queue.Push(&Car{ID: 3})
+	tests := []struct {
+		position int
+		want     bool
+	}{
+		{0, false},
+		{1, false},
+		{2, true},
+	}
+	for _, tt := range tests {
+		if got := lane.blockedAhead(tt.position); got != tt.want {
+			t.Errorf("blockedAhead(%d) = %v, want %v", tt.position, got, tt.want)
+		}
+	}
+	if load := lane.load(); load != 3 {
+		t.Errorf("load = %d, want 2 positions and 1 queued car", load)
+	}
+	if state := lane.state(); state.Occupied != 2 || state.Queue != 1 {
+		t.Errorf("state = %+v, want 2 occupied and 1 queued", state)
+	}
+}
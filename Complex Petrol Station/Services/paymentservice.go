@@ -0,0 +1,92 @@
+package Services
+
+import (
+	"fmt"
+	"time"
+)
+
+// Initializations
+
+type PaymentMethod string
+
+// Constants for payment methods
+const (
+	Cash      PaymentMethod = "cash"
+	Card      PaymentMethod = "card"
+	FleetCard PaymentMethod = "fleet_card"
+	App       PaymentMethod = "app"
+)
+
+// PaymentMethods lists all payment methods in their choice order
+var PaymentMethods = []PaymentMethod{Cash, Card, FleetCard, App}
+
+// PaymentSetup describes how customers pay with a method
+type PaymentSetup struct {
+	Share       float64 // relative share of cars paying with the method
+	MinT        int     // minimal time of one attempt at a staffed register
+	MaxT        int     // maximal time of one attempt at a staffed register
+	FailureRate float64 // probability that an attempt is declined
+	Retries     int     // attempts repeated after declines, the last one always goes through
+}
+
+// Utilities
+
+// ParsePaymentMethod converts a payment method name to its constant
+func ParsePaymentMethod(name string) (PaymentMethod, error) {
+	for _, method := range PaymentMethods {
+		if string(method) == name {
+			return method, nil
+		}
+	}
+	return "", fmt.Errorf("unknown payment method %q", name)
+}
+
+// choosePaymentMethod picks the payment method of the car by the method shares, none without any
+func (st *Station) choosePaymentMethod() PaymentMethod {
+	total := 0.0
+	for _, method := range PaymentMethods {
+		total += st.Payments[method].Share
+	}
+	if total <= 0 {
+		return ""
+	}
+	draw := st.rng.paymentMethod.Float64() * total
+	cumulative := 0.0
+	for _, method := range PaymentMethods {
+		cumulative += st.Payments[method].Share
+		if draw < cumulative {
+			return method
+		}
+	}
+	return PaymentMethods[len(PaymentMethods)-1]
+}
+
+// drawPayment draws the payment attempts of the car and sets its total payment time
+//
+// The first attempt uses the payment time stream, repeated ones their own
+// streams so that declines do not shift the payment times of later cars.
+func (st *Station) drawPayment(car *Car) {
+	minT, maxT := st.paymentRange(car)
+	car.payAttempts = []time.Duration{randomTime(st.rng.payTime, minT, maxT)}
+	setup := st.Payments[car.PaymentMethod]
+	for len(car.payAttempts) <= setup.Retries && st.rng.paymentFailure.Float64() < setup.FailureRate {
+		car.payAttempts = append(car.payAttempts, randomTime(st.rng.retryTime, minT, maxT))
+	}
+	for _, attempt := range car.payAttempts {
+		car.PayTime += attempt
+	}
+	car.PaymentAttempts = len(car.payAttempts)
+}
+
+// paymentRange returns the time range of a payment attempt of the car
+//
+// Self-service registers keep their own times, staffed ones take the time of the payment method.
+func (st *Station) paymentRange(car *Car) (int, int) {
+	if setup, ok := st.RegisterClasses[car.RegisterClass]; ok && car.RegisterClass != Staffed {
+		return setup.MinT, setup.MaxT
+	}
+	if setup, ok := st.Payments[car.PaymentMethod]; ok && car.PaymentMethod != "" {
+		return setup.MinT, setup.MaxT
+	}
+	return st.MinPaymentT, st.MaxPaymentT
+}
@@ -0,0 +1,112 @@
+package Services
+
+import (
+	"math"
+	"testing"
+)
+
+func TestParsePaymentMethod(t *testing.T) {
+	tests := []struct {
+		name  string
+		want  PaymentMethod
+		valid bool
+	}{
+		{"cash", Cash, true},
+		{"fleet_card", FleetCard, true},
+		{"app", App, true},
+		{"Card", "", false},
+		{"cheque", "", false},
+	}
+	for _, tt := range tests {
+		got, err := ParsePaymentMethod(tt.name)
+		if got != tt.want || (err == nil) != tt.valid {
+			t.Errorf("ParsePaymentMethod(%q) = %q, %v, want %q valid %v", tt.name, got, err, tt.want, tt.valid)
+		}
+	}
+}
+
+func TestChoosePaymentMethod(t *testing.T) {
+	tests := []struct {
+		name     string
+		payments map[PaymentMethod]PaymentSetup
+		want     map[PaymentMethod]float64
+	}{
+		{"no methods", nil, map[PaymentMethod]float64{"": 1}},
+		{"relative shares", map[PaymentMethod]PaymentSetup{Cash: {Share: 1}, Card: {Share: 3}}, map[PaymentMethod]float64{Cash: 0.25, Card: 0.75}},
+		{"single method", map[PaymentMethod]PaymentSetup{App: {Share: 0.1}}, map[PaymentMethod]float64{App: 1}},
+	}
+	const draws = 20000
+	for _, tt := range tests {
+		st := &Station{Payments: tt.payments, rng: newRandomStreams(9)}
+		counts := make(map[PaymentMethod]int)
+		for i := 0; i < draws; i++ {
+			counts[st.choosePaymentMethod()]++
+		}
+		for _, method := range append([]PaymentMethod{""}, PaymentMethods...) {
+			if share := float64(counts[method]) / draws; math.Abs(share-tt.want[method]) > 0.02 {
+				t.Errorf("%s: %q share = %.3f, want %.2f", tt.name, method, share, tt.want[method])
+			}
+		}
+	}
+}
+
+func TestDrawPayment(t *testing.T) {
+	tests := []struct {
+		name     string
+		setup    PaymentSetup
+		attempts int
+	}{
+		{"never declined", PaymentSetup{Share: 1, MinT: 4, MaxT: 5, FailureRate: 0, Retries: 3}, 1},
+		{"always declined", PaymentSetup{Share: 1, MinT: 4, MaxT: 5, FailureRate: 1, Retries: 2}, 3},
+		{"declined without retries", PaymentSetup{Share: 1, MinT: 4, MaxT: 5, FailureRate: 1}, 1},
+	}
+	for _, tt := range tests {
+		st := &Station{Payments: map[PaymentMethod]PaymentSetup{Card: tt.setup}, rng: newRandomStreams(3)}
+		car := &Car{PaymentMethod: Card}
+		st.drawPayment(car)
+		if car.PaymentAttempts != tt.attempts || int(car.PayTime) != 4*tt.attempts {
+			t.Errorf("%s: %d attempts taking %d, want %d taking %d", tt.name, car.PaymentAttempts, car.PayTime, tt.attempts, 4*tt.attempts)
+		}
+	}
+}
+
+func TestPaymentRange(t *testing.T) {
+	st := &Station{
+		MinPaymentT: 1, MaxPaymentT: 10,
+		Payments:        map[PaymentMethod]PaymentSetup{Cash: {Share: 1, MinT: 20, MaxT: 30}},
+		RegisterClasses: map[RegisterClass]RegisterSetup{Kiosk: {Count: 1, MinT: 5, MaxT: 6}},
+	}
+	tests := []struct {
+		name     string
+		car      Car
+		min, max int
+	}{
+		{"without method", Car{}, 1, 10},
+		{"method at a staffed register", Car{PaymentMethod: Cash, RegisterClass: Staffed}, 20, 30},
+		{"method without setup", Car{PaymentMethod: App}, 1, 10},
+		{"self-service register", Car{PaymentMethod: Cash, RegisterClass: Kiosk}, 5, 6},
+	}
+	for _, tt := range tests {
+		if min, max := st.paymentRange(&tt.car); min != tt.min || max != tt.max {
+			t.Errorf("%s: range = %d..%d, want %d..%d", tt.name, min, max, tt.min, tt.max)
+		}
+	}
+}
+
+func TestRegisterSetupEligibleMethods(t *testing.T) {
+	kiosk := RegisterSetup{Methods: []PaymentMethod{Card, FleetCard}}
+	tests := []struct {
+		method PaymentMethod
+		want   bool
+	}{
+		{Card, true},
+		{FleetCard, true},
+		{Cash, false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := kiosk.eligible(&Car{Fuel: Gas, PaymentMethod: tt.method}); got != tt.want {
+			t.Errorf("eligible paying with %q = %v, want %v", tt.method, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package Services
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Initializations
+
+type Stage string
+
+// Constants for the service stages of the station
+const (
+	StageFuel    Stage = "fuel"
+	StagePayment Stage = "payment"
+	StageWash    Stage = "wash"
+)
+
+// Stages lists all service stages
+var Stages = []Stage{StageFuel, StagePayment, StageWash}
+
+// DefaultPipeline is the order of stages when the station sets none
+var DefaultPipeline = []Stage{StageFuel, StagePayment}
+
+// Utilities
+
+// ParseStage converts a stage name to its constant
+func ParseStage(name string) (Stage, error) {
+	for _, stage := range Stages {
+		if string(stage) == name {
+			return stage, nil
+		}
+	}
+	return "", fmt.Errorf("unknown stage %q", name)
+}
+
+// pipeline returns the order of stages every car goes through
+func (st *Station) pipeline() []Stage {
+	if len(st.Pipeline) == 0 {
+		return DefaultPipeline
+	}
+	return st.Pipeline
+}
+
+// nextStage returns the index of the next stage the car takes part in, past the end when it leaves
+func (st *Station) nextStage(car *Car) int {
+	stage := car.stage + 1
+	for stage < len(st.pipeline()) && st.pipeline()[stage] == StageWash && !car.Wash {
+		stage++
+	}
+	return stage
+}
+
+// holdsStand reports whether the car pays right after fueling and keeps its stand until then
+func (st *Station) holdsStand(car *Car) bool {
+	next := st.nextStage(car)
+	return next < len(st.pipeline()) && st.pipeline()[next] == StagePayment
+}
+
+// advance sends a car finished with its current stage to the next one, or out of the station
+func (st *Station) advance(car *Car, location int) {
+	car.stage = st.nextStage(car)
+	if car.stage >= len(st.pipeline()) {
+		st.exit(car)
+		return
+	}
+	switch st.pipeline()[car.stage] {
+	case StagePayment:
+		st.payments.Add(1)
+		st.logEvent(car, EventBuildingQueue, location)
+		st.BuildingQueue <- car
+	case StageWash:
+		car.washQueueEnter = time.Now()
+		st.logEvent(car, EventWashQueue, location)
+		st.washQueue.Push(car)
+	}
+}
+
+// exit sends a car leaving the station to the exit queue
+func (st *Station) exit(car *Car) {
+	st.logEvent(car, EventExit, -1)
+	st.Served.Add(1)
+	st.fuelCounts[car.Fuel].served.Add(1)
+	st.Exit <- car
+}
+
+// stageWaiter returns the wait group of the routines serving a stage
+func (st *Station) stageWaiter(stage Stage) *sync.WaitGroup {
+	switch stage {
+	case StagePayment:
+		return &st.registerWaiter
+	case StageWash:
+		return &st.washWaiter
+	}
+	return &st.standWaiter
+}
+
+// closeStage closes the queue leading to a stage once no more cars can enter it
+//
+// Cars still paying may be sent back to the building queue by closing registers,
+// so it stays open until every payment has finished.
+func (st *Station) closeStage(stage Stage) {
+	switch stage {
+	case StagePayment:
+		st.payments.Wait()
+		close(st.BuildingQueue)
+	case StageWash:
+		st.washQueue.Close()
+	}
+}
@@ -0,0 +1,56 @@
+package Services
+
+import (
+	"testing"
+)
+
+func TestParseStage(t *testing.T) {
+	tests := []struct {
+		name  string
+		want  Stage
+		valid bool
+	}{
+		{"fuel", StageFuel, true},
+		{"payment", StagePayment, true},
+		{"wash", StageWash, true},
+		{"shop", "", false},
+		{"Wash", "", false},
+	}
+	for _, tt := range tests {
+		got, err := ParseStage(tt.name)
+		if got != tt.want || (err == nil) != tt.valid {
+			t.Errorf("ParseStage(%q) = %q, %v, want %q valid %v", tt.name, got, err, tt.want, tt.valid)
+		}
+	}
+}
+
+func TestNextStage(t *testing.T) {
+	washFirst := []Stage{StageFuel, StageWash, StagePayment}
+	washLast := []Stage{StageFuel, StagePayment, StageWash}
+	tests := []struct {
+		name     string
+		pipeline []Stage
+		stage    int
+		wash     bool
+		next     int
+		holds    bool
+	}{
+		{"default pipeline", nil, 0, false, 1, true},
+		{"default pipeline paid", nil, 1, false, 2, false},
+		{"wash before paying", washFirst, 0, true, 1, false},
+		{"wash skipped before paying", washFirst, 0, false, 2, true},
+		{"paying after the wash", washFirst, 1, true, 2, true},
+		{"wash after paying", washLast, 1, true, 2, false},
+		{"wash skipped after paying", washLast, 1, false, 3, false},
+	}
+	for _, tt := range tests {
+		st := &Station{Pipeline: tt.pipeline}
+		car := &Car{stage: tt.stage, Wash: tt.wash}
+		if got := st.nextStage(car); got != tt.next {
+			t.Errorf("%s: nextStage = %d, want %d", tt.name, got, tt.next)
+		}
+		if got := st.holdsStand(car); got != tt.holds {
+			t.Errorf("%s: holdsStand = %v, want %v", tt.name, got, tt.holds)
+		}
+	}
+}
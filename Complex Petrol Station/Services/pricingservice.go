@@ -0,0 +1,49 @@
+package Services
+
+// Initializations
+
+// Pricer sets the fuel prices of a running station
+type Pricer interface {
+	// Price returns the current price per unit of the fuel
+	Price(fuel FuelType) float64
+	// Demand returns the demand for the fuel at the price relative to the demand at its base price
+	Demand(fuel FuelType, price float64) float64
+}
+
+// Utilities
+
+// quote prices an arriving car and returns how many cars actually come with it
+//
+// Demand below one makes the car balk with the missing share as probability,
+// demand above one brings an extra car of the same fuel with the surplus as probability.
+func (st *Station) quote(car *Car) int {
+	if st.Pricer == nil {
+		return 1
+	}
+	car.Price = st.Pricer.Price(car.Fuel)
+	demand := st.Pricer.Demand(car.Fuel, car.Price)
+	draw := st.rng.balk.Float64()
+	switch {
+	case demand < 1 && draw >= demand:
+		return 0
+	case demand > 1 && draw < demand-1:
+		return 2
+	}
+	return 1
+}
+
+// FuelQueue returns the number of cars queued at the stands of the fuel
+func (st *Station) FuelQueue(fuel FuelType) int {
+	queued := 0
+	for _, stand := range st.Stands {
+		if stand.Sells(fuel) {
+			queued += stand.Queue.Len()
+		}
+	}
+	for _, lane := range st.Lanes {
+		if lane.Type == fuel {
+			queued += lane.Queue.Len()
+		}
+	}
+	return queued
+}
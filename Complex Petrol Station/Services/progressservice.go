@@ -0,0 +1,202 @@
+package Services
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Initializations
+
+// Constant for the number of recent waiting times in rolling averages
+const rollingWindow = 50
+
+// QueueState describes the queue of a single stand or register
+type QueueState struct {
+	Id          int           `json:"id"`
+	Fuel        FuelType      `json:"fuel,omitempty"`
+	Fuels       []FuelType    `json:"fuels,omitempty"` // fuels of a multi-product stand
+	Class       RegisterClass `json:"class,omitempty"`
+	Queue       int           `json:"queue"`
+	Serving     int           `json:"serving"` // id of the car being served, -1 when idle
+	ServingFuel FuelType      `json:"serving_fuel,omitempty"`
+	Utilization float64       `json:"utilization"`      // share of the run spent occupied
+	Closed      bool          `json:"closed,omitempty"` // register without a cashier
+}
+
+// FuelState counts the cars of one fuel type
+type FuelState struct {
+	Fuel    FuelType `json:"fuel"`
+	Arrived int      `json:"arrived"`
+	Served  int      `json:"served"`
+	Balked  int      `json:"balked"`
+	Price   float64  `json:"price,omitempty"` // current price, zero without a pricing strategy
+}
+
+// Snapshot describes the running state of the station at one moment
+type Snapshot struct {
+	Time            int64        `json:"time"`
+	CarsArrived     int          `json:"cars_arrived"`
+	CarsServed      int          `json:"cars_served"`
+	Stands          []QueueState `json:"stands"`
+	Registers       []QueueState `json:"registers"`
+	Lanes           []LaneState  `json:"lanes,omitempty"`
+	WashBays        []QueueState `json:"wash_bays,omitempty"`
+	WashQueue       int          `json:"wash_queue"`
+	Fuels           []FuelState  `json:"fuels"`
+	BuildingQueue   int          `json:"building_queue"`
+	ArrivalBacklog  int          `json:"arrival_backlog"` // arrived cars not yet assigned to a stand
+	AvgStandWait    float64      `json:"avg_stand_wait"`
+	AvgRegisterWait float64      `json:"avg_register_wait"`
+}
+
+// fuelCounters counts the cars of one fuel type passing the station
+type fuelCounters struct {
+	arrived atomic.Int64
+	served  atomic.Int64
+	balked  atomic.Int64
+}
+
+// occupancy tracks which car occupies a stand or register and for how long it was busy
+type occupancy struct {
+	mu      sync.Mutex
+	current *Car
+	since   time.Time
+	busy    time.Duration
+}
+
+// occupy marks the car as being served
+func (o *occupancy) occupy(car *Car) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.current = car
+	o.since = time.Now()
+}
+
+// release marks the served car as gone
+func (o *occupancy) release() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.current != nil {
+		o.busy += time.Since(o.since)
+		o.current = nil
+	}
+}
+
+// state returns the served car id and fuel and the busy share of the elapsed time
+func (o *occupancy) state(elapsed time.Duration) (int, FuelType, float64) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	serving := -1
+	var fuel FuelType
+	busy := o.busy
+	if o.current != nil {
+		serving = o.current.ID
+		fuel = o.current.Fuel
+		busy += time.Since(o.since)
+	}
+	if elapsed <= 0 {
+		return serving, fuel, 0
+	}
+	return serving, fuel, min(float64(busy)/float64(elapsed), 1)
+}
+
+// rollingAverage keeps the average of the last few recorded values
+type rollingAverage struct {
+	mu     sync.Mutex
+	window int // number of values kept, rollingWindow when zero
+	values []float64
+	next   int
+}
+
+// add records a new value, replacing the oldest one once the window is full
+func (ra *rollingAverage) add(value float64) {
+	ra.mu.Lock()
+	defer ra.mu.Unlock()
+	window := ra.window
+	if window == 0 {
+		window = rollingWindow
+	}
+	if len(ra.values) < window {
+		ra.values = append(ra.values, value)
+		return
+	}
+	ra.values[ra.next] = value
+	ra.next = (ra.next + 1) % len(ra.values)
+}
+
+// average returns the average of the recorded values
+func (ra *rollingAverage) average() float64 {
+	ra.mu.Lock()
+	defer ra.mu.Unlock()
+	if len(ra.values) == 0 {
+		return 0
+	}
+	total := 0.0
+	for _, value := range ra.values {
+		total += value
+	}
+	return total / float64(len(ra.values))
+}
+
+// Snapshot captures the current queues and running averages of an open station
+func (st *Station) Snapshot() Snapshot {
+	snapshot := Snapshot{
+		Time:            st.Elapsed(),
+		CarsArrived:     int(st.Arrived.Load()),
+		CarsServed:      int(st.Served.Load()),
+		BuildingQueue:   len(st.BuildingQueue),
+		ArrivalBacklog:  st.arrivals.Len(),
+		WashQueue:       st.washQueue.Len(),
+		AvgStandWait:    st.standWaits.average(),
+		AvgRegisterWait: st.registerWaits.average(),
+	}
+	elapsed := time.Since(st.start)
+	for _, stand := range st.Stands {
+		serving, fuel, utilization := stand.occupancy.state(elapsed)
+		state := QueueState{
+			Id:          stand.Id,
+			Fuel:        stand.Type,
+			Queue:       stand.Queue.Len(),
+			Serving:     serving,
+			ServingFuel: fuel,
+			Utilization: utilization,
+		}
+		if len(stand.Fuels) > 1 {
+			state.Fuels = stand.Fuels
+		}
+		snapshot.Stands = append(snapshot.Stands, state)
+	}
+	for _, bay := range st.WashBays {
+		serving, _, utilization := bay.occupancy.state(elapsed)
+		snapshot.WashBays = append(snapshot.WashBays, QueueState{Id: bay.Id, Serving: serving, Utilization: utilization})
+	}
+	for _, lane := range st.Lanes {
+		snapshot.Lanes = append(snapshot.Lanes, lane.state())
+	}
+	for _, register := range st.Registers {
+		serving, _, utilization := register.occupancy.state(elapsed)
+		snapshot.Registers = append(snapshot.Registers, QueueState{
+			Id:          register.Id,
+			Class:       register.Class,
+			Queue:       register.Queue.Len(),
+			Serving:     serving,
+			Utilization: utilization,
+			Closed:      !register.IsOpen(),
+		})
+	}
+	for _, fuel := range FuelTypes {
+		counts := st.fuelCounts[fuel]
+		state := FuelState{
+			Fuel:    fuel,
+			Arrived: int(counts.arrived.Load()),
+			Served:  int(counts.served.Load()),
+			Balked:  int(counts.balked.Load()),
+		}
+		if st.Pricer != nil {
+			state.Price = st.Pricer.Price(fuel)
+		}
+		snapshot.Fuels = append(snapshot.Fuels, state)
+	}
+	return snapshot
+}
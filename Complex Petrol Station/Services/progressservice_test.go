@@ -0,0 +1,59 @@
+package Services
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRollingAverage(t *testing.T) {
+	tests := []struct {
+		add  float64
+		want float64
+	}{
+		{3, 3},
+		{6, 4.5},
+		{9, 6},
+		// Replaces 3, then 6
+		{12, 9},
+		{0, 7},
+	}
+	ra := rollingAverage{window: 3}
+	if ra.average() != 0 {
+		t.Errorf("empty average = %v, want 0", ra.average())
+	}
+	for _, tt := range tests {
+		ra.add(tt.add)
+		if got := ra.average(); got != tt.want {
+			t.Errorf("after adding %v average = %v, want %v", tt.add, got, tt.want)
+		}
+	}
+}
+
+func TestOccupancyState(t *testing.T) {
+	var o occupancy
+	if serving, fuel, utilization := o.state(time.Second); serving != -1 || fuel != "" || utilization != 0 {
+		t.Errorf("idle state = %d %q %v, want -1, no fuel and 0", serving, fuel, utilization)
+	}
+	o.occupy(&Car{ID: 5, Fuel: Diesel})
+	if serving, fuel, _ := o.state(time.Second); serving != 5 || fuel != Diesel {
+		t.Errorf("busy state = %d %q, want car 5 with diesel", serving, fuel)
+	}
+	if _, _, utilization := o.state(0); utilization != 0 {
+		t.Errorf("utilization before any time elapsed = %v, want 0", utilization)
+	}
+	o.release()
+	o.busy = 300 * time.Millisecond
+	tests := []struct {
+		elapsed time.Duration
+		want    float64
+	}{
+		{time.Second, 0.3},
+		{600 * time.Millisecond, 0.5},
+		{100 * time.Millisecond, 1},
+	}
+	for _, tt := range tests {
+		if serving, _, got := o.state(tt.elapsed); serving != -1 || got != tt.want {
+			t.Errorf("state after %v = %d %v, want -1 and %v", tt.elapsed, serving, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package Services
+
+import (
+	"container/heap"
+	"sync"
+)
+
+// Initializations
+
+// CarQueue is a bounded queue serving cars by priority and in arrival order within a priority
+//
+// Higher priority cars jump ahead of waiting ones but never interrupt a car being served.
+type CarQueue struct {
+	mu       sync.Mutex
+	notEmpty *sync.Cond
+	notFull  *sync.Cond
+	cars     carHeap
+	capacity int
+	sequence int
+	closed   bool
+}
+
+// queuedCar is a car waiting in a queue with its position in arrival order
+type queuedCar struct {
+	car      *Car
+	sequence int
+}
+
+// carHeap orders waiting cars by priority, then by arrival
+type carHeap []queuedCar
+
+// NewCarQueue creates a queue holding at most capacity waiting cars
+func NewCarQueue(capacity int) *CarQueue {
+	q := &CarQueue{capacity: max(capacity, 1)}
+	q.notEmpty = sync.NewCond(&q.mu)
+	q.notFull = sync.NewCond(&q.mu)
+	return q
+}
+
+// Utilities
+
+// Push adds a car to the queue, waiting while the queue is full
+func (q *CarQueue) Push(car *Car) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for len(q.cars) >= q.capacity && !q.closed {
+		q.notFull.Wait()
+	}
+	if q.closed {
+		panic("push to a closed car queue")
+	}
+	heap.Push(&q.cars, queuedCar{car, q.sequence})
+	q.sequence++
+	q.notEmpty.Signal()
+}
+
+// Pop removes the next car, waiting while the queue is empty, and reports false once it is closed and drained
+func (q *CarQueue) Pop() (*Car, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for len(q.cars) == 0 && !q.closed {
+		q.notEmpty.Wait()
+	}
+	if len(q.cars) == 0 {
+		return nil, false
+	}
+	queued := heap.Pop(&q.cars).(queuedCar)
+	q.notFull.Signal()
+	return queued.car, true
+}
+
+// Close stops the queue, cars already waiting are still handed out
+func (q *CarQueue) Close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.closed = true
+	q.notEmpty.Broadcast()
+	q.notFull.Broadcast()
+}
+
+// Len returns the number of waiting cars, zero before the queue is created
+func (q *CarQueue) Len() int {
+	if q == nil {
+		return 0
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.cars)
+}
+
+// Len implements heap.Interface
+func (h carHeap) Len() int { return len(h) }
+
+// Less implements heap.Interface
+func (h carHeap) Less(i, j int) bool {
+	if h[i].car.Priority != h[j].car.Priority {
+		return h[i].car.Priority > h[j].car.Priority
+	}
+	return h[i].sequence < h[j].sequence
+}
+
+// Swap implements heap.Interface
+func (h carHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+
+// Push implements heap.Interface
+func (h *carHeap) Push(x any) { *h = append(*h, x.(queuedCar)) }
+
+// Pop implements heap.Interface
+func (h *carHeap) Pop() any {
+	old := *h
+	last := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return last
+}
@@ -0,0 +1,145 @@
+package Services
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestCarQueueOrder(t *testing.T) {
+	car := func(id, priority int) *Car { return &Car{ID: id, Priority: priority} }
+	tests := []struct {
+		name string
+		cars []*Car
+		want []int
+	}{
+		{"arrival order", []*Car{car(1, 0), car(2, 0), car(3, 0)}, []int{1, 2, 3}},
+		{"priority first", []*Car{car(1, 0), car(2, 5), car(3, 1)}, []int{2, 3, 1}},
+		{"arrival order within a priority", []*Car{car(1, 1), car(2, 0), car(3, 1), car(4, 0), car(5, 1)}, []int{1, 3, 5, 2, 4}},
+		{"many equal cars", []*Car{car(1, 2), car(2, 2), car(3, 2), car(4, 2), car(5, 2), car(6, 2), car(7, 2)}, []int{1, 2, 3, 4, 5, 6, 7}},
+	}
+	for _, tt := range tests {
+		q := NewCarQueue(len(tt.cars))
+		for _, c := range tt.cars {
+			q.Push(c)
+		}
+		q.Close()
+		var got []int
+		for c, ok := q.Pop(); ok; c, ok = q.Pop() {
+			got = append(got, c.ID)
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("%s: popped %v, want %v", tt.name, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("%s: popped %v, want %v", tt.name, got, tt.want)
+				break
+			}
+		}
+	}
+}
+
+func TestCarQueueInterleaved(t *testing.T) {
+	// A later emergency car overtakes cars still waiting but not those already served
+	q := NewCarQueue(4)
+	q.Push(&Car{ID: 1})
+	q.Push(&Car{ID: 2})
+	first, _ := q.Pop()
+	q.Push(&Car{ID: 3, Priority: 2})
+	second, _ := q.Pop()
+	third, _ := q.Pop()
+	if first.ID != 1 || second.ID != 3 || third.ID != 2 {
+		t.Errorf("popped %d, %d, %d, want 1, 3, 2", first.ID, second.ID, third.ID)
+	}
+}
+
+func TestCarQueueCapacity(t *testing.T) {
+	q := NewCarQueue(1)
+	q.Push(&Car{ID: 1})
+	pushed := make(chan struct{})
+	go func() {
+		q.Push(&Car{ID: 2})
+		close(pushed)
+	}()
+	select {
+	case <-pushed:
+		t.Fatal("push to a full queue did not wait")
+	case <-time.After(20 * time.Millisecond):
+	}
+	if q.Len() != 1 {
+		t.Errorf("full queue length = %d, want 1", q.Len())
+	}
+	if c, _ := q.Pop(); c.ID != 1 {
+		t.Errorf("popped car %d, want 1", c.ID)
+	}
+	select {
+	case <-pushed:
+	case <-time.After(time.Second):
+		t.Fatal("push did not continue after a pop")
+	}
+	if c, _ := q.Pop(); c.ID != 2 {
+		t.Errorf("popped car %d, want 2", c.ID)
+	}
+}
+
+func TestCarQueueClose(t *testing.T) {
+	q := NewCarQueue(2)
+	popped := make(chan bool)
+	go func() {
+		_, ok := q.Pop()
+		popped <- ok
+	}()
+	time.Sleep(10 * time.Millisecond)
+	q.Close()
+	select {
+	case ok := <-popped:
+		if ok {
+			t.Error("pop from a closed empty queue reported a car")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("close did not wake a waiting pop")
+	}
+	defer func() {
+		if recover() == nil {
+			t.Error("push to a closed queue did not panic")
+		}
+	}()
+	q.Push(&Car{ID: 1})
+}
+
+func TestCarQueueLen(t *testing.T) {
+	var missing *CarQueue
+	if missing.Len() != 0 {
+		t.Errorf("nil queue length = %d, want 0", missing.Len())
+	}
+	if q := NewCarQueue(0); q.capacity != 1 {
+		t.Errorf("queue without capacity holds %d cars, want 1", q.capacity)
+	}
+}
+
+func TestChooseCarClass(t *testing.T) {
+	tests := []struct {
+		name    string
+		classes map[CarClass]ClassSetup
+		want    map[CarClass]float64
+	}{
+		{"no classes", nil, map[CarClass]float64{Regular: 1}},
+		{"shares", map[CarClass]ClassSetup{Regular: {}, Emergency: {Share: 0.1, Priority: 2}, Fleet: {Share: 0.3, Priority: 1}},
+			map[CarClass]float64{Regular: 0.6, Emergency: 0.1, Fleet: 0.3}},
+	}
+	const draws = 20000
+	for _, tt := range tests {
+		st := &Station{Classes: tt.classes, rng: newRandomStreams(11)}
+		counts := make(map[CarClass]int)
+		for i := 0; i < draws; i++ {
+			counts[st.chooseCarClass()]++
+		}
+		for _, class := range append(PriorityClasses, Regular) {
+			if share := float64(counts[class]) / draws; math.Abs(share-tt.want[class]) > 0.02 {
+				t.Errorf("%s: %s share = %.3f, want %.2f", tt.name, class, share, tt.want[class])
+			}
+		}
+	}
+}
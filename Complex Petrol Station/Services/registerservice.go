@@ -2,98 +2,187 @@ package Services
 
 import (
 	"fmt"
-	"sync"
 	"time"
 )
 
-// Variables
-
-// Register setups
-
-var RegisterWaiter sync.WaitGroup
-var RegisterBuffer = 3
-
-// Register numbers
-
-var NumRegisters = 2
-
-// Payment times
-
-var MinPaymentT = 1
-var MaxPaymentT = 7
-
-// Building
-
-var BuildingQueue = make(chan *Car, 10)
+// Initializations
 
-// Exit
+type RegisterClass string
 
-var Exit = make(chan *Car)
+// Constants for register classes
+const (
+	Staffed RegisterClass = "staffed"
+	Kiosk   RegisterClass = "kiosk"
+	Mobile  RegisterClass = "mobile"
+)
 
-// Initializations
+// SelfServiceClasses lists the register classes without a cashier in their choice order
+var SelfServiceClasses = []RegisterClass{Kiosk, Mobile}
+
+// RegisterSetup describes the registers of a self-service class
+type RegisterSetup struct {
+	Count      int             // number of registers
+	MinT       int             // minimal payment time
+	MaxT       int             // maximal payment time
+	Preference float64         // share of cars preferring the class
+	MaxBasket  float64         // larger shop baskets are not eligible, no limit when zero
+	Fuels      []FuelType      // eligible fuel types, all when empty
+	Methods    []PaymentMethod // eligible payment methods, all when empty
+}
 
 // CashRegister represents a cash register for payment
 type CashRegister struct {
-	Id    int
-	Queue chan *Car
+	Id       int
+	Class    RegisterClass
+	Queue    *CarQueue
+	staffing staffingState
+	occupancy
 }
 
 // NewCashRegister creates a new cash register
-func NewCashRegister(id, bufferSize int) *CashRegister {
+func NewCashRegister(id int, class RegisterClass, bufferSize int) *CashRegister {
 	return &CashRegister{
 		Id:    id,
-		Queue: make(chan *Car, bufferSize),
+		Class: class,
+		Queue: NewCarQueue(bufferSize),
 	}
 }
 
 // Routines
 
 // FindRegister finds the best cash register for a customer
-func FindRegister(registers []*CashRegister) {
+func (st *Station) FindRegister() {
 	// Station building queue
-	for car := range BuildingQueue {
-		var bestRegister *CashRegister
-		bestQueueLength := -1
-		// Finding best register
-		for _, register := range registers {
-			queueLength := len(register.Queue)
-			if bestQueueLength == -1 || queueLength < bestQueueLength {
-				bestRegister = register
-				bestQueueLength = queueLength
-			}
+	for car := range st.BuildingQueue {
+		// Waiting for a cashier while all registers of the class are closed
+		st.registerMu.Lock()
+		bestRegister := st.bestRegister(car)
+		for bestRegister == nil {
+			st.registerOpened.Wait()
+			bestRegister = st.bestRegister(car)
 		}
-		car.RegisterQueueEnter = time.Now()
-		bestRegister.Queue <- car
+		st.registerMu.Unlock()
+		// Redirected cars keep waiting since they first queued
+		if car.RegisterQueueEnter.IsZero() {
+			car.RegisterQueueEnter = time.Now()
+		}
+		st.logEvent(car, EventRegisterAssigned, bestRegister.Id)
+		bestRegister.Queue.Push(car)
 	}
 	// Closing all registers
-	for _, register := range registers {
-		close(register.Queue)
+	for _, register := range st.Registers {
+		register.Queue.Close()
 	}
 }
 
 // RegisterRoutine runs a routine for serving cars at a register
-func RegisterRoutine(cs *CashRegister) {
-	defer RegisterWaiter.Done()
-	RegisterWaiter.Add(1)
-	fmt.Printf("Cash register %d is open\n", cs.Id)
+func (st *Station) RegisterRoutine(cs *CashRegister) {
+	defer st.registerWaiter.Done()
+	fmt.Fprintf(st.Log, "Cash register %d is open\n", cs.Id)
 	// Station shop queue
-	for car := range cs.Queue {
+	for car, ok := cs.Queue.Pop(); ok; car, ok = cs.Queue.Pop() {
+		// Closed registers send their queue to the open ones
+		if !cs.IsOpen() {
+			st.BuildingQueue <- car
+			continue
+		}
 		car.RegisterQueueTime = time.Duration(time.Since(car.RegisterQueueEnter).Milliseconds())
-		doPayment(car)
+		st.registerWaits.add(float64(car.RegisterQueueTime))
+		cs.occupy(car)
+		st.logEvent(car, EventPaymentStart, cs.Id)
+		st.doPayment(car, cs)
+		cs.release()
+		st.logEvent(car, EventPaymentEnd, cs.Id)
 		// Signaling finished payment to stand
-		car.carSync.Done()
-		// Sending car to exit queue
-		Exit <- car
+		if car.heldStand {
+			car.heldStand = false
+			car.carSync.Done()
+		}
+		st.advance(car, cs.Id)
+		st.payments.Done()
 	}
-	fmt.Printf("Cash register %d is closed\n", cs.Id)
+	fmt.Fprintf(st.Log, "Cash register %d is closed\n", cs.Id)
 }
 
 // Utilities
 
+// bestRegister returns the open register of the class chosen by the car with the shortest queue
+//
+// Must be called with the register lock held, nil when all registers of the class are closed.
+func (st *Station) bestRegister(car *Car) *CashRegister {
+	var bestRegister *CashRegister
+	bestQueueLength := -1
+	for _, register := range st.Registers {
+		queueLength := register.Queue.Len()
+		if register.Class == car.RegisterClass && register.IsOpen() && (bestQueueLength == -1 || queueLength < bestQueueLength) {
+			bestRegister = register
+			bestQueueLength = queueLength
+		}
+	}
+	return bestRegister
+}
+
+// chooseRegisterClass picks the class of register the car pays at
+//
+// The car prefers a class with its probability and falls back to the staffed
+// registers when it is not eligible for the preferred one.
+func (st *Station) chooseRegisterClass(car *Car) RegisterClass {
+	draw := st.rng.registerClass.Float64()
+	cumulative := 0.0
+	for _, class := range SelfServiceClasses {
+		setup := st.RegisterClasses[class]
+		cumulative += setup.Preference
+		if draw < cumulative {
+			if setup.Count > 0 && setup.eligible(car) {
+				return class
+			}
+			break
+		}
+	}
+	return Staffed
+}
+
+// eligible reports whether the car may pay at registers of the class
+func (setup RegisterSetup) eligible(car *Car) bool {
+	if setup.MaxBasket > 0 && car.ShopBasket > setup.MaxBasket {
+		return false
+	}
+	if len(setup.Methods) > 0 && !containsMethod(setup.Methods, car.PaymentMethod) {
+		return false
+	}
+	if len(setup.Fuels) == 0 {
+		return true
+	}
+	for _, fuel := range setup.Fuels {
+		if fuel == car.Fuel {
+			return true
+		}
+	}
+	return false
+}
+
+// containsMethod reports whether the payment method is listed
+func containsMethod(methods []PaymentMethod, method PaymentMethod) bool {
+	for _, m := range methods {
+		if m == method {
+			return true
+		}
+	}
+	return false
+}
+
 // doPayment does payment
-func doPayment(car *Car) {
-	// Generating payment time
-	car.PayTime = randomTime(MinPaymentT, MaxPaymentT)
-	// Waiting for payment to finish
-	doSleeping(car.PayTime)
+func (st *Station) doPayment(car *Car, cs *CashRegister) {
+	// Recorded cars pay in a single attempt
+	if len(car.payAttempts) == 0 {
+		doSleeping(car.PayTime)
+		return
+	}
+	// Waiting for every attempt, all but the last are declined
+	for i, attempt := range car.payAttempts {
+		doSleeping(attempt)
+		if i < len(car.payAttempts)-1 {
+			st.logEvent(car, EventPaymentDeclined, cs.Id)
+		}
+	}
 }
@@ -0,0 +1,107 @@
+package Services
+
+import (
+	"io"
+	"math"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRegisterSetupEligible(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup RegisterSetup
+		car   Car
+		want  bool
+	}{
+		{"no limits", RegisterSetup{}, Car{Fuel: LPG, ShopBasket: 80}, true},
+		{"small basket", RegisterSetup{MaxBasket: 20}, Car{Fuel: Gas, ShopBasket: 20}, true},
+		{"large basket", RegisterSetup{MaxBasket: 20}, Car{Fuel: Gas, ShopBasket: 20.5}, false},
+		{"listed fuel", RegisterSetup{Fuels: []FuelType{Gas, Electric}}, Car{Fuel: Electric}, true},
+		{"other fuel", RegisterSetup{Fuels: []FuelType{Gas, Electric}}, Car{Fuel: Diesel}, false},
+	}
+	for _, tt := range tests {
+		if got := tt.setup.eligible(&tt.car); got != tt.want {
+			t.Errorf("%s: eligible = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestChooseRegisterClass(t *testing.T) {
+	tests := []struct {
+		name    string
+		classes map[RegisterClass]RegisterSetup
+		car     Car
+		want    map[RegisterClass]float64
+	}{
+		{"no self-service", nil, Car{Fuel: Gas}, map[RegisterClass]float64{Staffed: 1}},
+		{
+			"preferences",
+			map[RegisterClass]RegisterSetup{Kiosk: {Count: 1, Preference: 0.5}, Mobile: {Count: 1, Preference: 0.2}},
+			Car{Fuel: Gas},
+			map[RegisterClass]float64{Staffed: 0.3, Kiosk: 0.5, Mobile: 0.2},
+		},
+		{
+			"preferred class without registers",
+			map[RegisterClass]RegisterSetup{Kiosk: {Preference: 0.5}, Mobile: {Count: 1, Preference: 0.2}},
+			Car{Fuel: Gas},
+			map[RegisterClass]float64{Staffed: 0.8, Mobile: 0.2},
+		},
+		{
+			"not eligible falls back to staffed",
+			map[RegisterClass]RegisterSetup{Kiosk: {Count: 1, Preference: 0.5, Fuels: []FuelType{Electric}}, Mobile: {Count: 1, Preference: 0.2}},
+			Car{Fuel: Gas},
+			map[RegisterClass]float64{Staffed: 0.8, Mobile: 0.2},
+		},
+	}
+	const draws = 20000
+	for _, tt := range tests {
+		st := &Station{RegisterClasses: tt.classes, rng: newRandomStreams(5)}
+		counts := make(map[RegisterClass]int)
+		for i := 0; i < draws; i++ {
+			counts[st.chooseRegisterClass(&tt.car)]++
+		}
+		for _, class := range append([]RegisterClass{Staffed}, SelfServiceClasses...) {
+			if share := float64(counts[class]) / draws; math.Abs(share-tt.want[class]) > 0.02 {
+				t.Errorf("%s: %s share = %.3f, want %.2f", tt.name, class, share, tt.want[class])
+			}
+		}
+	}
+}
+
+func TestStaffedTimeSkipsSelfService(t *testing.T) {
+	st := &Station{Registers: []*CashRegister{NewCashRegister(0, Kiosk, 1), NewCashRegister(1, Staffed, 1), NewCashRegister(2, Mobile, 1)}}
+	staffing := st.StaffedTime()
+	if len(staffing) != 1 || staffing[0].Id != 1 {
+		t.Errorf("staffed time = %+v, want only register 1", staffing)
+	}
+}
+
+func TestFindRegisterWaitsForOpening(t *testing.T) {
+	// The only register starts closed until the staffing update opens it
+	register := NewCashRegister(0, Staffed, 1)
+	st := &Station{Registers: []*CashRegister{register}, BuildingQueue: make(chan *Car, 1), Log: io.Discard}
+	st.registerOpened = sync.NewCond(&st.registerMu)
+	go st.FindRegister()
+	defer close(st.BuildingQueue)
+	st.BuildingQueue <- &Car{ID: 1, RegisterClass: Staffed}
+	time.Sleep(20 * time.Millisecond)
+	if register.Queue.Len() != 0 {
+		t.Fatal("car was sent to a closed register")
+	}
+	st.updateStaffing()
+	assigned := make(chan *Car)
+	go func() {
+		car, _ := register.Queue.Pop()
+		assigned <- car
+	}()
+	select {
+	case car := <-assigned:
+		if car.ID != 1 {
+			t.Errorf("register got car %d, want 1", car.ID)
+		}
+	case <-time.After(time.Second):
+		t.Error("car still waits after the register opened")
+	}
+}
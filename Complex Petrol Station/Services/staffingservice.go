@@ -0,0 +1,178 @@
+package Services
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Initializations
+
+// Window is a period of simulated ms, open ended when To is zero
+type Window struct {
+	From int64
+	To   int64
+}
+
+// Staffing describes when the cashier of a register is at work
+type Staffing struct {
+	Windows       []Window // staffed periods, staffed for the whole run when empty
+	Breaks        []Window // periods the cashier is away
+	OnDemandAbove int      // when positive the register only opens while more cars wait to pay
+	OnDemandClose int      // waiting cars at or below which an on-demand register closes again
+}
+
+// staffingState tracks whether a register is open and for how long it was staffed
+type staffingState struct {
+	mu       sync.Mutex
+	open     bool
+	since    time.Time
+	staffed  time.Duration
+	openings int
+}
+
+// RegisterStaffing describes how long a register was staffed
+type RegisterStaffing struct {
+	Id       int
+	Staffed  time.Duration // simulated ms
+	Openings int
+}
+
+// Routines
+
+// staffingRoutine opens and closes registers by their staffing until the station closes
+func (st *Station) staffingRoutine() {
+	defer st.staffingWaiter.Done()
+	ticker := time.NewTicker(time.Millisecond)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-st.closed:
+			return
+		case <-ticker.C:
+			st.updateStaffing()
+		}
+	}
+}
+
+// Utilities
+
+// updateStaffing opens registers whose cashier should be at work and closes the others
+func (st *Station) updateStaffing() {
+	now := st.Elapsed()
+	waiting := st.WaitingToPay()
+	for _, register := range st.Registers {
+		open := st.staffed(register, now, waiting)
+		if open != register.IsOpen() {
+			st.registerMu.Lock()
+			register.setOpen(open)
+			if open {
+				st.registerOpened.Broadcast()
+			}
+			st.registerMu.Unlock()
+			if open {
+				fmt.Fprintf(st.Log, "Cash register %d opens\n", register.Id)
+			} else {
+				fmt.Fprintf(st.Log, "Cash register %d closes\n", register.Id)
+			}
+		}
+	}
+}
+
+// staffed reports whether the register should have a cashier at the moment
+func (st *Station) staffed(register *CashRegister, now int64, waiting int) bool {
+	staffing, ok := st.Staffing[register.Id]
+	if !ok {
+		return true
+	}
+	open := staffing.staffedAt(now)
+	if staffing.OnDemandAbove > 0 && open {
+		// On-demand registers keep their state between the thresholds
+		switch {
+		case waiting > staffing.OnDemandAbove:
+		case waiting <= staffing.OnDemandClose:
+			open = false
+		default:
+			open = register.IsOpen()
+		}
+	}
+	return open
+}
+
+// staffedAt reports whether the schedule has the cashier at work at the moment
+func (staffing Staffing) staffedAt(now int64) bool {
+	for _, b := range staffing.Breaks {
+		if b.contains(now) {
+			return false
+		}
+	}
+	if len(staffing.Windows) == 0 {
+		return true
+	}
+	for _, w := range staffing.Windows {
+		if w.contains(now) {
+			return true
+		}
+	}
+	return false
+}
+
+// contains reports whether the moment falls into the window
+func (w Window) contains(now int64) bool {
+	return now >= w.From && (w.To == 0 || now < w.To)
+}
+
+// IsOpen reports whether the register accepts new customers
+func (cs *CashRegister) IsOpen() bool {
+	cs.staffing.mu.Lock()
+	defer cs.staffing.mu.Unlock()
+	return cs.staffing.open
+}
+
+// setOpen opens or closes the register, accounting for its staffed time
+func (cs *CashRegister) setOpen(open bool) {
+	cs.staffing.mu.Lock()
+	defer cs.staffing.mu.Unlock()
+	if open == cs.staffing.open {
+		return
+	}
+	if open {
+		cs.staffing.since = time.Now()
+		cs.staffing.openings++
+	} else {
+		cs.staffing.staffed += time.Since(cs.staffing.since)
+	}
+	cs.staffing.open = open
+}
+
+// WaitingToPay returns the number of fueled cars waiting for a register
+func (st *Station) WaitingToPay() int {
+	waiting := len(st.BuildingQueue)
+	for _, register := range st.Registers {
+		waiting += register.Queue.Len()
+	}
+	return waiting
+}
+
+// StaffedTime returns how long every staffed register had a cashier so far
+func (st *Station) StaffedTime() []RegisterStaffing {
+	var staffing []RegisterStaffing
+	for _, register := range st.Registers {
+		// Self-service registers need no cashier
+		if register.Class != Staffed {
+			continue
+		}
+		register.staffing.mu.Lock()
+		staffed := register.staffing.staffed
+		if register.staffing.open {
+			staffed += time.Since(register.staffing.since)
+		}
+		staffing = append(staffing, RegisterStaffing{
+			Id:       register.Id,
+			Staffed:  time.Duration(staffed.Milliseconds()),
+			Openings: register.staffing.openings,
+		})
+		register.staffing.mu.Unlock()
+	}
+	return staffing
+}
@@ -0,0 +1,74 @@
+package Services
+
+import (
+	"testing"
+)
+
+func TestStaffedAt(t *testing.T) {
+	shifts := Staffing{
+		Windows: []Window{{From: 0, To: 100}, {From: 200, To: 0}},
+		Breaks:  []Window{{From: 40, To: 60}, {From: 250, To: 300}},
+	}
+	tests := []struct {
+		name     string
+		staffing Staffing
+		now      int64
+		want     bool
+	}{
+		{"always", Staffing{}, 5000, true},
+		{"break without windows", Staffing{Breaks: []Window{{From: 10, To: 20}}}, 15, false},
+		{"after break without windows", Staffing{Breaks: []Window{{From: 10, To: 20}}}, 20, true},
+		{"first shift", shifts, 0, true},
+		{"break starts", shifts, 40, false},
+		{"break ends", shifts, 60, true},
+		{"first shift ends", shifts, 100, false},
+		{"between shifts", shifts, 150, false},
+		{"open ended shift", shifts, 200, true},
+		{"break in open ended shift", shifts, 299, false},
+		{"late", shifts, 100000, true},
+	}
+	for _, tt := range tests {
+		if got := tt.staffing.staffedAt(tt.now); got != tt.want {
+			t.Errorf("%s: staffedAt(%d) = %v, want %v", tt.name, tt.now, got, tt.want)
+		}
+	}
+}
+
+func TestStaffedOnDemand(t *testing.T) {
+	onDemand := Staffing{OnDemandAbove: 4, OnDemandClose: 1, Windows: []Window{{From: 0, To: 1000}}}
+	st := &Station{Staffing: map[int]Staffing{0: onDemand}}
+	tests := []struct {
+		name    string
+		id      int
+		open    bool
+		now     int64
+		waiting int
+		want    bool
+	}{
+		{"unscheduled register", 1, false, 5000, 0, true},
+		{"above threshold", 0, false, 10, 5, true},
+		{"between thresholds stays closed", 0, false, 10, 3, false},
+		{"between thresholds stays open", 0, true, 10, 2, true},
+		{"at close", 0, true, 10, 1, false},
+		{"outside the window", 0, true, 1000, 9, false},
+	}
+	for _, tt := range tests {
+		register := NewCashRegister(tt.id, Staffed, 1)
+		register.setOpen(tt.open)
+		if got := st.staffed(register, tt.now, tt.waiting); got != tt.want {
+			t.Errorf("%s: staffed = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestStaffedTime(t *testing.T) {
+	st := &Station{Registers: []*CashRegister{NewCashRegister(0, Staffed, 1)}}
+	register := st.Registers[0]
+	register.setOpen(true)
+	register.setOpen(false)
+	register.setOpen(true)
+	staffing := st.StaffedTime()
+	if len(staffing) != 1 || staffing[0].Id != 0 || staffing[0].Openings != 2 || staffing[0].Staffed < 0 {
+		t.Errorf("staffed time = %+v, want register 0 opened twice", staffing)
+	}
+}
@@ -2,43 +2,28 @@ package Services
 
 import (
 	"fmt"
-	"sync"
 	"time"
 )
 
-// Variables
-
-// Fueling times
-
-var GasMinT = 1
-var GasMaxT = 4
-var DieselMinT = 2
-var DieselMaxT = 5
-var LpgMinT = 5
-var LpgMaxT = 12
-var ElectricMinT = 10
-var ElectricMaxT = 21
-
-// Stand setups
-
-var StandFinishWaiter sync.WaitGroup
-var StandCreationWaiter sync.WaitGroup
-var StandBuffer = 2
-
-// Stand numbers
-
-var NumGas = 2
-var NumDiesel = 2
-var NumLPG = 1
-var NumElectric = 1
-
 // Initializations
 
+// DispenserSetup describes multi-product stands selling several fuels from one position
+type DispenserSetup struct {
+	Count int
+	Fuels []FuelType
+	MinT  map[FuelType]int // fueling time per fuel, the fuel setup applies when missing
+	MaxT  map[FuelType]int
+}
+
 // FuelStand describes a specific stand at the station
 type FuelStand struct {
 	Id    int
-	Type  FuelType
-	Queue chan *Car
+	Type  FuelType   // first fuel sold at the stand
+	Fuels []FuelType // all fuels sold at the stand
+	Queue *CarQueue
+	minT  map[FuelType]int
+	maxT  map[FuelType]int
+	occupancy
 }
 
 // NewFuelStand creates a stand for specific fuel type
@@ -46,71 +31,130 @@ func NewFuelStand(id int, fuel FuelType, bufferSize int) *FuelStand {
 	return &FuelStand{
 		Id:    id,
 		Type:  fuel,
-		Queue: make(chan *Car, bufferSize),
+		Fuels: []FuelType{fuel},
+		Queue: NewCarQueue(bufferSize),
 	}
 }
 
+// NewDispenser creates a stand selling all fuels of the dispenser setup
+func NewDispenser(id int, setup DispenserSetup, bufferSize int) *FuelStand {
+	stand := NewFuelStand(id, setup.Fuels[0], bufferSize)
+	stand.Fuels = setup.Fuels
+	stand.minT = setup.MinT
+	stand.maxT = setup.MaxT
+	return stand
+}
+
 // Routines
 
 // FindStandRoutine finds the best stand according to fuel type
-func FindStandRoutine(stands []*FuelStand) {
+func (st *Station) FindStandRoutine() {
 	// Station entrance queue
-	for car := range arrivals {
+	for car, ok := st.arrivals.Pop(); ok; car, ok = st.arrivals.Pop() {
+		// Islands queue cars per lane
+		if len(st.Lanes) > 0 {
+			st.assignLane(car)
+			continue
+		}
 		// Initialization
 		var bestStand *FuelStand
 		bestQueueLength := -1
 		// Finding best stand
-		for _, stand := range stands {
-			if stand.Type == car.Fuel {
-				queueLength := len(stand.Queue)
+		for _, stand := range st.Stands {
+			if stand.Sells(car.Fuel) {
+				queueLength := stand.Queue.Len()
 				if bestQueueLength == -1 || queueLength < bestQueueLength {
 					bestStand = stand
 					bestQueueLength = queueLength
 				}
 			}
 		}
-		bestStand.Queue <- car
+		st.logEvent(car, EventStandAssigned, bestStand.Id)
+		bestStand.Queue.Push(car)
 	}
 	// Closing all stands
-	for _, stand := range stands {
-		close(stand.Queue)
+	for _, stand := range st.Stands {
+		stand.Queue.Close()
+	}
+	for _, lane := range st.Lanes {
+		lane.Queue.Close()
 	}
 }
 
 // StandRoutine runs a routine for serving cars at a stand
-func StandRoutine(fs *FuelStand) {
-	defer StandFinishWaiter.Done()
-	StandFinishWaiter.Add(1)
-	fmt.Printf("Fuel stand %d is open\n", fs.Id)
-	StandCreationWaiter.Done()
+func (st *Station) StandRoutine(fs *FuelStand) {
+	defer st.standWaiter.Done()
+	fmt.Fprintf(st.Log, "Fuel stand %d is open\n", fs.Id)
 	// Stand queue
-	for car := range fs.Queue {
-		car.StandQueueTime = time.Duration(time.Since(car.StandQueueEnter).Milliseconds())
-		doFueling(car)
-		car.carSync.Add(1)
-		// Sending car to registers
-		BuildingQueue <- car
-		// Wait for payment to complete
-		car.carSync.Wait()
+	for car, ok := fs.Queue.Pop(); ok; car, ok = fs.Queue.Pop() {
+		st.serveAtStand(fs, car)
+		fs.release()
 	}
-	fmt.Printf("Fuel stand %d is closed\n", fs.Id)
+	fmt.Fprintf(st.Log, "Fuel stand %d is closed\n", fs.Id)
 }
 
 // Utilities
 
-// doFueling does fueling
-func doFueling(car *Car) {
-	// Set fuel time according to fuel type
-	switch car.Fuel {
-	case Gas:
-		car.FuelTime = randomTime(GasMinT, GasMaxT)
-	case Diesel:
-		car.FuelTime = randomTime(DieselMinT, DieselMaxT)
-	case LPG:
-		car.FuelTime = randomTime(LpgMinT, LpgMaxT)
-	case Electric:
-		car.FuelTime = randomTime(ElectricMinT, ElectricMaxT)
+// serveAtStand fuels the car at the stand and keeps it there until it has paid
+func (st *Station) serveAtStand(fs *FuelStand, car *Car) {
+	car.StandQueueTime = time.Duration(time.Since(car.StandQueueEnter).Milliseconds())
+	car.Stand = fs.Id
+	fs.setFuelTime(car)
+	st.standWaits.add(float64(car.StandQueueTime))
+	fs.occupy(car)
+	st.logEvent(car, EventFuelStart, fs.Id)
+	st.doFueling(car)
+	st.logEvent(car, EventFuelEnd, fs.Id)
+	// Cars paying right after fueling stay at the stand until they have paid
+	car.heldStand = st.holdsStand(car)
+	if car.heldStand {
+		car.carSync.Add(1)
 	}
+	st.advance(car, fs.Id)
+	car.carSync.Wait()
+}
+
+// Sells reports whether the stand sells the fuel
+func (fs *FuelStand) Sells(fuel FuelType) bool {
+	for _, f := range fs.Fuels {
+		if f == fuel {
+			return true
+		}
+	}
+	return false
+}
+
+// setFuelTime replaces the drawn fueling time of the car by the time of the stand for its fuel
+//
+// The car keeps the random draw it got on arrival so the time does not depend on the service order.
+func (fs *FuelStand) setFuelTime(car *Car) {
+	minT, ok := fs.minT[car.Fuel]
+	if !ok || !car.fuelTimeDrawn {
+		return
+	}
+	maxT := fs.maxT[car.Fuel]
+	car.FuelTime = time.Duration(minT + int(car.fuelDraw*float64(maxT-minT)))
+}
+
+// assignLane sends the car to the least loaded lane of its fuel
+func (st *Station) assignLane(car *Car) {
+	var bestLane *Lane
+	bestLoad := -1
+	for _, lane := range st.Lanes {
+		if lane.Type == car.Fuel {
+			load := lane.load()
+			if bestLoad == -1 || load < bestLoad {
+				bestLane = lane
+				bestLoad = load
+			}
+		}
+	}
+	st.logEvent(car, EventStandAssigned, bestLane.Positions[0].Id)
+	bestLane.Queue.Push(car)
+}
+
+// doFueling does fueling
+func (st *Station) doFueling(car *Car) {
 	// Wait to finish fueling
 	doSleeping(car.FuelTime)
 }
@@ -0,0 +1,53 @@
+package Services
+
+import (
+	"testing"
+)
+
+func TestDispenserSells(t *testing.T) {
+	single := NewFuelStand(1, LPG, 1)
+	dispenser := NewDispenser(2, DispenserSetup{Count: 1, Fuels: []FuelType{Gas, Diesel}}, 1)
+	if dispenser.Type != Gas || dispenser.Id != 2 {
+		t.Errorf("dispenser = %d selling %s first, want stand 2 selling gas first", dispenser.Id, dispenser.Type)
+	}
+	tests := []struct {
+		name  string
+		stand *FuelStand
+		fuel  FuelType
+		want  bool
+	}{
+		{"single fuel", single, LPG, true},
+		{"other fuel", single, Gas, false},
+		{"first dispenser fuel", dispenser, Gas, true},
+		{"second dispenser fuel", dispenser, Diesel, true},
+		{"fuel not dispensed", dispenser, Electric, false},
+	}
+	for _, tt := range tests {
+		if got := tt.stand.Sells(tt.fuel); got != tt.want {
+			t.Errorf("%s: Sells(%s) = %v, want %v", tt.name, tt.fuel, got, tt.want)
+		}
+	}
+}
+
+func TestSetFuelTime(t *testing.T) {
+	dispenser := NewDispenser(0, DispenserSetup{Count: 1, Fuels: []FuelType{Gas, Diesel},
+		MinT: map[FuelType]int{Diesel: 10}, MaxT: map[FuelType]int{Diesel: 20}}, 1)
+	tests := []struct {
+		name  string
+		stand *FuelStand
+		car   Car
+		want  int
+	}{
+		{"stand without own times", NewFuelStand(1, Diesel, 1), Car{Fuel: Diesel, FuelTime: 3, fuelDraw: 0.5, fuelTimeDrawn: true}, 3},
+		{"fuel without own times", dispenser, Car{Fuel: Gas, FuelTime: 3, fuelDraw: 0.5, fuelTimeDrawn: true}, 3},
+		{"lowest draw", dispenser, Car{Fuel: Diesel, FuelTime: 3, fuelDraw: 0, fuelTimeDrawn: true}, 10},
+		{"middle draw", dispenser, Car{Fuel: Diesel, FuelTime: 3, fuelDraw: 0.55, fuelTimeDrawn: true}, 15},
+		{"recorded time", dispenser, Car{Fuel: Diesel, FuelTime: 3, fuelDraw: 0.5}, 3},
+	}
+	for _, tt := range tests {
+		tt.stand.setFuelTime(&tt.car)
+		if int(tt.car.FuelTime) != tt.want {
+			t.Errorf("%s: fuel time = %d, want %d", tt.name, tt.car.FuelTime, tt.want)
+		}
+	}
+}
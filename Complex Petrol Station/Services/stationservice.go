@@ -0,0 +1,247 @@
+package Services
+
+import (
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Initializations
+
+// FuelSetup describes the stands serving one fuel type
+type FuelSetup struct {
+	Count     int     // number of stands
+	MinT      int     // minimal fueling time
+	MaxT      int     // maximal fueling time
+	MinVolume float64 // minimal fuel volume bought by a car
+	MaxVolume float64 // maximal fuel volume bought by a car, none are drawn when zero
+}
+
+// Station holds the setup and the runtime state of a single simulation run
+type Station struct {
+	// Seed of the random generator, a time based one is picked when zero
+	Seed int64
+	// Arrivals
+	StaggerMin int
+	StaggerMax int
+	CarNum     int
+	Trace      []TraceRecord
+	TraceScale float64
+	// Stands
+	Fuels       map[FuelType]FuelSetup
+	StandBuffer int
+	// Multi-product stands in addition to the stands of the fuels
+	Dispensers []DispenserSetup
+	// Pump islands replacing the stand counts of the fuels, stands are independent when empty
+	Islands []IslandSetup
+	// Registers
+	NumRegisters   int
+	MinPaymentT    int
+	MaxPaymentT    int
+	RegisterBuffer int
+	// Self-service registers by class, the staffed ones are set up above
+	RegisterClasses map[RegisterClass]RegisterSetup
+	// Priority car classes, all cars are regular when empty
+	Classes map[CarClass]ClassSetup
+	// Payment methods, cars pay without one when no method has a share
+	Payments map[PaymentMethod]PaymentSetup
+	// Staffing of registers by id, registers without one are staffed for the whole run
+	Staffing map[int]Staffing
+	// Car wash, cars only wash when the pipeline has a wash stage
+	Wash WashSetup
+	// Order of the stages every car goes through, DefaultPipeline when empty
+	Pipeline []Stage
+	// Shop baskets of generated cars, none are drawn when the maximum is zero
+	MinShopBasket float64
+	MaxShopBasket float64
+	// Pricing strategy, prices stay fixed and demand unaffected when nil
+	Pricer Pricer
+	// Event log, closed by the station once the run is over
+	Events chan Event
+	// Log receives opening and closing messages of stands and registers
+	Log io.Writer
+
+	// Runtime
+	Stands        []*FuelStand
+	Lanes         []*Lane
+	WashBays      []*WashBay
+	Registers     []*CashRegister
+	BuildingQueue chan *Car
+	Exit          chan *Car
+	arrivals      *CarQueue
+	washQueue     *CarQueue
+	closed        chan struct{}
+	start         time.Time
+	rng           randomStreams
+	// Progress
+	Arrived    atomic.Int64
+	Served     atomic.Int64
+	fuelCounts map[FuelType]*fuelCounters
+	extraCars  atomic.Int64
+	// Rolling waiting times
+	standWaits    rollingAverage
+	registerWaits rollingAverage
+	// Synchronization
+	standWaiter    sync.WaitGroup
+	registerWaiter sync.WaitGroup
+	staffingWaiter sync.WaitGroup
+	washWaiter     sync.WaitGroup
+	// Registers opening, signalled to cars waiting for a cashier
+	registerMu     sync.Mutex
+	registerOpened *sync.Cond
+	// Cars sent to pay that have not finished paying
+	payments sync.WaitGroup
+}
+
+// FuelTypes lists all fuel types in their configuration order
+var FuelTypes = []FuelType{Gas, Diesel, LPG, Electric}
+
+// NewStation creates a station with the default setup
+func NewStation() *Station {
+	return &Station{
+		StaggerMin: 1,
+		StaggerMax: 2,
+		CarNum:     100,
+		TraceScale: 1,
+		Fuels: map[FuelType]FuelSetup{
+			Gas:      {Count: 2, MinT: 1, MaxT: 4},
+			Diesel:   {Count: 2, MinT: 2, MaxT: 5},
+			LPG:      {Count: 1, MinT: 5, MaxT: 12},
+			Electric: {Count: 1, MinT: 10, MaxT: 21},
+		},
+		StandBuffer:    2,
+		NumRegisters:   2,
+		MinPaymentT:    1,
+		MaxPaymentT:    7,
+		RegisterBuffer: 3,
+		Log:            os.Stdout,
+	}
+}
+
+// TotalCars returns the number of cars planned to arrive during the run
+func (st *Station) TotalCars() int {
+	if st.Trace != nil {
+		return len(st.Trace)
+	}
+	return st.CarNum
+}
+
+// Elapsed returns simulated milliseconds since the station opened
+func (st *Station) Elapsed() int64 {
+	return time.Since(st.start).Milliseconds()
+}
+
+// Routines
+
+// Open builds the stands and registers and starts all station routines
+//
+// Finished cars are sent to Exit which is closed once the last car leaves.
+func (st *Station) Open() {
+	if st.Seed == 0 {
+		st.Seed = time.Now().UnixNano()
+	}
+	st.rng = newRandomStreams(st.Seed)
+	st.arrivals = NewCarQueue(20)
+	st.BuildingQueue = make(chan *Car, 10)
+	st.registerOpened = sync.NewCond(&st.registerMu)
+	st.Exit = make(chan *Car)
+	st.closed = make(chan struct{})
+	st.washQueue = NewCarQueue(st.Wash.Buffer)
+	st.fuelCounts = make(map[FuelType]*fuelCounters)
+	for _, fuel := range FuelTypes {
+		st.fuelCounts[fuel] = &fuelCounters{}
+	}
+	// Creating fuel stands, or the lanes of the islands with a stand per position
+	for i, island := range st.Islands {
+		for side := 0; side < island.Sides; side++ {
+			lane := NewLane(len(st.Lanes), i, side, island.Fuel, island.Positions, len(st.Stands), st.StandBuffer)
+			st.Lanes = append(st.Lanes, lane)
+			st.Stands = append(st.Stands, lane.Positions...)
+		}
+	}
+	for _, fuel := range FuelTypes {
+		for i := 0; i < st.Fuels[fuel].Count && len(st.Islands) == 0; i++ {
+			st.Stands = append(st.Stands, NewFuelStand(len(st.Stands), fuel, st.StandBuffer))
+		}
+	}
+	for _, dispenser := range st.Dispensers {
+		for i := 0; i < dispenser.Count && len(st.Islands) == 0; i++ {
+			st.Stands = append(st.Stands, NewDispenser(len(st.Stands), dispenser, st.StandBuffer))
+		}
+	}
+	// Creating registers
+	for i := 0; i < st.NumRegisters; i++ {
+		st.Registers = append(st.Registers, NewCashRegister(i, Staffed, st.RegisterBuffer))
+	}
+	for _, class := range SelfServiceClasses {
+		for i := 0; i < st.RegisterClasses[class].Count; i++ {
+			st.Registers = append(st.Registers, NewCashRegister(len(st.Registers), class, st.RegisterBuffer))
+		}
+	}
+	// Creating wash bays
+	for i := 0; i < st.Wash.Bays; i++ {
+		st.WashBays = append(st.WashBays, NewWashBay(i))
+	}
+	st.start = time.Now()
+	for _, register := range st.Registers {
+		register.setOpen(st.staffed(register, 0, 0))
+	}
+	// Car creation routine
+	go st.CreateCarsRoutine()
+	// Stand routines, lanes serve their own positions
+	if len(st.Lanes) > 0 {
+		st.standWaiter.Add(len(st.Lanes))
+		for _, lane := range st.Lanes {
+			go st.LaneRoutine(lane)
+		}
+	} else {
+		st.standWaiter.Add(len(st.Stands))
+		for _, stand := range st.Stands {
+			go st.StandRoutine(stand)
+		}
+	}
+	// CashRegister routines
+	st.registerWaiter.Add(len(st.Registers))
+	for _, register := range st.Registers {
+		go st.RegisterRoutine(register)
+	}
+	// Wash bay routines
+	st.washWaiter.Add(len(st.WashBays))
+	for _, bay := range st.WashBays {
+		go st.WashRoutine(bay)
+	}
+	// Car shuffling routine
+	go st.FindStandRoutine()
+	// Register shuffling routine
+	go st.FindRegister()
+	// Register staffing routine
+	if st.Staffing != nil {
+		st.staffingWaiter.Add(1)
+		go st.staffingRoutine()
+	}
+	// End synchronization routine
+	go st.closeRoutine()
+}
+
+// closeRoutine closes the station queues once all routines have finished
+func (st *Station) closeRoutine() {
+	// Closing every stage once the stage before it has finished
+	pipeline := st.pipeline()
+	for i := 1; i < len(pipeline); i++ {
+		st.stageWaiter(pipeline[i-1]).Wait()
+		st.closeStage(pipeline[i])
+	}
+	st.stageWaiter(pipeline[len(pipeline)-1]).Wait()
+	close(st.closed)
+	st.staffingWaiter.Wait()
+	// Cashiers go home with the last car
+	for _, register := range st.Registers {
+		register.setOpen(false)
+	}
+	close(st.Exit)
+	if st.Events != nil {
+		close(st.Events)
+	}
+}
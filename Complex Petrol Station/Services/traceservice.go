@@ -0,0 +1,158 @@
+package Services
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Initializations
+
+// TraceRecord describes one recorded customer visit from a transaction log
+type TraceRecord struct {
+	Arrival    time.Time
+	Fuel       FuelType
+	FuelTime   *float64 // nil when not recorded
+	PayTime    *float64 // nil when not recorded
+	ShopBasket float64
+}
+
+// traceTimeLayouts are the accepted timestamp formats of a trace file
+var traceTimeLayouts = []string{
+	time.RFC3339,
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04:05",
+	"15:04:05",
+}
+
+// LoadTrace reads arrival records from a csv trace file
+//
+// Columns: timestamp, fuel type, fuel duration, payment duration, shop basket.
+// Durations are in seconds and may be left empty to be generated randomly.
+// Timestamps are either absolute times or seconds from the start of the trace.
+// A trace without records is an error, as it would replay no cars.
+func LoadTrace(path string) ([]TraceRecord, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	var records []TraceRecord
+	line := 0
+	for {
+		fields, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		line++
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("line %d: expected at least timestamp and fuel type", line)
+		}
+		arrival, err := parseTraceTime(fields[0])
+		if err != nil {
+			// Skipping header row
+			if line == 1 {
+				continue
+			}
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+		fuel, err := ParseFuelType(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+		record := TraceRecord{Arrival: arrival, Fuel: fuel}
+		optional := []**float64{&record.FuelTime, &record.PayTime, nil}
+		for i, target := range optional {
+			if len(fields) <= i+2 || strings.TrimSpace(fields[i+2]) == "" {
+				continue
+			}
+			value, err := strconv.ParseFloat(strings.TrimSpace(fields[i+2]), 64)
+			if err != nil || value < 0 {
+				return nil, fmt.Errorf("line %d: invalid value %q", line, fields[i+2])
+			}
+			if target == nil {
+				record.ShopBasket = value
+			} else {
+				*target = &value
+			}
+		}
+		records = append(records, record)
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("trace has no records")
+	}
+	return records, nil
+}
+
+// ParseFuelType converts a fuel name into a fuel type regardless of case
+func ParseFuelType(name string) (FuelType, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "gas", "petrol", "gasoline":
+		return Gas, nil
+	case "diesel":
+		return Diesel, nil
+	case "lpg":
+		return LPG, nil
+	case "electric", "ev":
+		return Electric, nil
+	}
+	return "", fmt.Errorf("unknown fuel type %q", name)
+}
+
+// Routines
+
+// replayTraceRoutine injects recorded cars into the station at scaled intervals
+func (st *Station) replayTraceRoutine() {
+	for i, record := range st.Trace {
+		if i > 0 {
+			gap := record.Arrival.Sub(st.Trace[i-1].Arrival).Seconds()
+			if gap > 0 {
+				doSleeping(st.scaleTraceTime(gap))
+			}
+		}
+		car := &Car{ID: i, Fuel: record.Fuel, ShopBasket: record.ShopBasket}
+		if record.FuelTime != nil {
+			car.FuelTime = st.scaleTraceTime(*record.FuelTime)
+			car.fuelTimeRecorded = true
+		}
+		if record.PayTime != nil {
+			car.PayTime = st.scaleTraceTime(*record.PayTime)
+			car.payTimeRecorded = true
+		}
+		st.arrive(car)
+	}
+	st.arrivals.Close()
+}
+
+// Utilities
+
+// parseTraceTime parses an absolute timestamp or a numeric offset in seconds
+func parseTraceTime(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil {
+		return time.Time{}.Add(time.Duration(seconds * float64(time.Second))), nil
+	}
+	for _, layout := range traceTimeLayouts {
+		if parsed, err := time.Parse(layout, value); err == nil {
+			return parsed, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid timestamp %q", value)
+}
+
+// scaleTraceTime converts recorded seconds into simulated milliseconds, rounded to the nearest one
+func (st *Station) scaleTraceTime(seconds float64) time.Duration {
+	return time.Duration(math.Round(seconds * st.TraceScale))
+}
@@ -0,0 +1,131 @@
+package Services
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeTrace(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "trace.csv")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadTraceWithoutRecords(t *testing.T) {
+	for name, content := range map[string]string{
+		"empty":       "",
+		"header only": "timestamp,fuel,fuel_time,pay_time,basket\n",
+	} {
+		if records, err := LoadTrace(writeTrace(t, content)); err == nil {
+			t.Errorf("%s: got %d records and no error", name, len(records))
+		}
+	}
+}
+
+func TestLoadTraceOptionalDurations(t *testing.T) {
+	records, err := LoadTrace(writeTrace(t, "timestamp,fuel,fuel_time,pay_time,basket\n0,gas,0,,12.5\n1.5,diesel,,2\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("got %d records, want 2", len(records))
+	}
+	if records[0].FuelTime == nil || *records[0].FuelTime != 0 || records[0].PayTime != nil || records[0].ShopBasket != 12.5 {
+		t.Errorf("first record = %+v, want recorded zero fuel time, no pay time, basket 12.5", records[0])
+	}
+	if records[1].FuelTime != nil || records[1].PayTime == nil || *records[1].PayTime != 2 || records[1].Fuel != Diesel {
+		t.Errorf("second record = %+v, want diesel with only a pay time", records[1])
+	}
+}
+
+func TestScaleTraceTime(t *testing.T) {
+	tests := []struct {
+		seconds, scale float64
+		want           time.Duration
+	}{
+		{0.4, 1, 0},
+		{0.6, 1, 1},
+		{2.5, 1, 3},
+		{0.4, 10, 4},
+		{1.26, 100, 126},
+	}
+	for _, tt := range tests {
+		st := &Station{TraceScale: tt.scale}
+		if got := st.scaleTraceTime(tt.seconds); got != tt.want {
+			t.Errorf("scaleTraceTime(%v) at scale %v = %v, want %v", tt.seconds, tt.scale, got, tt.want)
+		}
+	}
+}
+
+func TestReplayKeepsRecordedZeroTimes(t *testing.T) {
+	records, err := LoadTrace(writeTrace(t, "0,gas,0.4,0\n0,gas,,\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	st := NewStation()
+	st.Seed = 1
+	st.Log = io.Discard
+	st.Trace = records
+	st.Open()
+	cars := make(map[int]*Car)
+	for car := range st.Exit {
+		cars[car.ID] = car
+	}
+	if len(cars) != 2 {
+		t.Fatalf("got %d cars, want 2", len(cars))
+	}
+	if cars[0].FuelTime != 0 || cars[0].PayTime != 0 {
+		t.Errorf("recorded car has fuel time %v and pay time %v, want 0 and 0", cars[0].FuelTime, cars[0].PayTime)
+	}
+	if setup := st.Fuels[Gas]; cars[1].FuelTime < time.Duration(setup.MinT) || cars[1].PayTime < time.Duration(st.MinPaymentT) {
+		t.Errorf("car without recorded times has fuel time %v and pay time %v, want drawn ones", cars[1].FuelTime, cars[1].PayTime)
+	}
+}
+
+func TestParseTraceTime(t *testing.T) {
+	zero := time.Time{}
+	day := time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC)
+	tests := []struct {
+		value string
+		want  time.Time
+		valid bool
+	}{
+		{"12.5", zero.Add(12500 * time.Millisecond), true},
+		{" 3 ", zero.Add(3 * time.Second), true},
+		{"08:15:30", time.Date(0, 1, 1, 8, 15, 30, 0, time.UTC), true},
+		{"2024-05-01 08:00:01", day.Add(time.Second), true},
+		{"2024-05-01T08:00:02", day.Add(2 * time.Second), true},
+		{"2024-05-01T08:00:03Z", day.Add(3 * time.Second), true},
+		{"timestamp", zero, false},
+	}
+	for _, tt := range tests {
+		got, err := parseTraceTime(tt.value)
+		if (err == nil) != tt.valid || !got.Equal(tt.want) {
+			t.Errorf("parseTraceTime(%q) = %v, %v, want %v valid %v", tt.value, got, err, tt.want, tt.valid)
+		}
+	}
+}
+
+func TestParseFuelType(t *testing.T) {
+	tests := []struct {
+		name string
+		want FuelType
+	}{
+		{"gas", Gas}, {"Petrol", Gas}, {"GASOLINE", Gas},
+		{"diesel", Diesel}, {" lpg ", LPG}, {"EV", Electric}, {"electric", Electric},
+	}
+	for _, tt := range tests {
+		if got, err := ParseFuelType(tt.name); err != nil || got != tt.want {
+			t.Errorf("ParseFuelType(%q) = %v, %v, want %v", tt.name, got, err, tt.want)
+		}
+	}
+	if _, err := ParseFuelType("hydrogen"); err == nil {
+		t.Error("unknown fuel type was accepted")
+	}
+}
@@ -2,22 +2,102 @@ package Services
 
 import (
 	"math/rand"
+	"sync"
 	"time"
 )
 
+// lockedRand is a seeded random generator shared by the routines of a station
+type lockedRand struct {
+	mu  sync.Mutex
+	rng *rand.Rand
+}
+
+// newLockedRand creates a random generator for the seed
+func newLockedRand(seed int64) *lockedRand {
+	return &lockedRand{rng: rand.New(rand.NewSource(seed))}
+}
+
+// Intn returns a random number in [0, n)
+func (r *lockedRand) Intn(n int) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.rng.Intn(n)
+}
+
+// Float64 returns a random number in [0, 1)
+func (r *lockedRand) Float64() float64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.rng.Float64()
+}
+
+// randomStreams holds one random generator per drawn quantity
+//
+// Separate streams keep e.g. the fuel types of the cars identical between runs
+// with the same seed even when other quantities are drawn a different number of times.
+type randomStreams struct {
+	arrival  *lockedRand
+	fuel     *lockedRand
+	fuelTime *lockedRand
+	payTime  *lockedRand
+	volume   *lockedRand
+	basket   *lockedRand
+	balk     *lockedRand
+	// Register class preference
+	registerClass *lockedRand
+	// Payment methods and declined attempts
+	paymentMethod  *lockedRand
+	paymentFailure *lockedRand
+	retryTime      *lockedRand
+	// Car classes
+	carClass *lockedRand
+	// Fueling times at dispensers with their own times
+	dispenserTime *lockedRand
+	// Car wash purchases and programs
+	wash     *lockedRand
+	washTime *lockedRand
+}
+
+// newRandomStreams derives all random streams from a single seed
+func newRandomStreams(seed int64) randomStreams {
+	master := rand.New(rand.NewSource(seed))
+	return randomStreams{
+		arrival:        newLockedRand(master.Int63()),
+		fuel:           newLockedRand(master.Int63()),
+		fuelTime:       newLockedRand(master.Int63()),
+		payTime:        newLockedRand(master.Int63()),
+		volume:         newLockedRand(master.Int63()),
+		basket:         newLockedRand(master.Int63()),
+		balk:           newLockedRand(master.Int63()),
+		registerClass:  newLockedRand(master.Int63()),
+		paymentMethod:  newLockedRand(master.Int63()),
+		paymentFailure: newLockedRand(master.Int63()),
+		retryTime:      newLockedRand(master.Int63()),
+		carClass:       newLockedRand(master.Int63()),
+		dispenserTime:  newLockedRand(master.Int63()),
+		wash:           newLockedRand(master.Int63()),
+		washTime:       newLockedRand(master.Int63()),
+	}
+}
+
 // genFuelType returns a random fuel type.
-func genFuelType() FuelType {
+func (st *Station) genFuelType() FuelType {
 	fuelTypes := []FuelType{Gas, Diesel, Electric, LPG}
-	randomIndex := rand.Intn(len(fuelTypes))
+	randomIndex := st.rng.fuel.Intn(len(fuelTypes))
 	return fuelTypes[randomIndex]
 }
 
 // randomTime generates a random time between min and max
-func randomTime(min, max int) time.Duration {
-	generatedTime := time.Duration(rand.Intn(max-min) + min)
+func randomTime(rng *lockedRand, min, max int) time.Duration {
+	generatedTime := time.Duration(rng.Intn(max-min) + min)
 	return generatedTime
 }
 
+// randomAmount generates a random amount between min and max
+func randomAmount(rng *lockedRand, min, max float64) float64 {
+	return min + rng.Float64()*(max-min)
+}
+
 // doSleeping sleeps for delay * milliseconds
 func doSleeping(delay time.Duration) {
 	time.Sleep(delay * time.Millisecond)
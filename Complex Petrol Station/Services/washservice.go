@@ -0,0 +1,60 @@
+package Services
+
+import (
+	"fmt"
+	"time"
+)
+
+// Initializations
+
+// WashSetup describes the car wash of the station
+type WashSetup struct {
+	Bays   int     // number of wash bays, no wash when zero
+	Share  float64 // share of cars buying a wash
+	MinT   int     // minimal wash program time
+	MaxT   int     // maximal wash program time
+	Buffer int     // cars waiting for a bay
+}
+
+// WashBay describes a single bay of the car wash
+type WashBay struct {
+	Id int
+	occupancy
+}
+
+// NewWashBay creates a wash bay
+func NewWashBay(id int) *WashBay {
+	return &WashBay{Id: id}
+}
+
+// Routines
+
+// WashRoutine runs a routine for washing cars in a bay
+func (st *Station) WashRoutine(bay *WashBay) {
+	defer st.washWaiter.Done()
+	fmt.Fprintf(st.Log, "Wash bay %d is open\n", bay.Id)
+	// Shared wash queue
+	for car, ok := st.washQueue.Pop(); ok; car, ok = st.washQueue.Pop() {
+		car.WashQueueTime = time.Duration(time.Since(car.washQueueEnter).Milliseconds())
+		bay.occupy(car)
+		st.logEvent(car, EventWashStart, bay.Id)
+		doSleeping(car.WashTime)
+		bay.release()
+		st.logEvent(car, EventWashEnd, bay.Id)
+		st.advance(car, bay.Id)
+	}
+	fmt.Fprintf(st.Log, "Wash bay %d is closed\n", bay.Id)
+}
+
+// Utilities
+
+// drawWash decides whether the car buys a wash and how long its program takes
+func (st *Station) drawWash(car *Car) {
+	if st.Wash.Bays == 0 {
+		return
+	}
+	car.Wash = st.rng.wash.Float64() < st.Wash.Share
+	if car.Wash {
+		car.WashTime = randomTime(st.rng.washTime, st.Wash.MinT, st.Wash.MaxT)
+	}
+}
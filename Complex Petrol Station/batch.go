@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math/rand"
+	"sync"
+)
+
+// Initializations
+
+// batchRun is a single simulation of a batch of runs
+type batchRun struct {
+	label  string
+	config Config
+	stats  FinalStats
+}
+
+// Routines
+
+// runBatch runs simulations on a bounded number of parallel stations
+//
+// The config seed of every run is updated to the seed the run actually used.
+func runBatch(runs []*batchRun, parallel int, progress io.Writer) error {
+	jobs := make(chan *batchRun)
+	errs := make(chan error, len(runs))
+	var workers sync.WaitGroup
+	var done sync.Mutex
+	finished := 0
+	for i := 0; i < parallel; i++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for run := range jobs {
+				station, err := run.config.newStation()
+				if err != nil {
+					errs <- err
+					continue
+				}
+				station.Log = io.Discard
+				run.stats = simulate(station, run.config)
+				run.config.Seed = station.Seed
+				done.Lock()
+				finished++
+				fmt.Fprintf(progress, "[%d/%d] %s\n", finished, len(runs), run.label)
+				done.Unlock()
+			}
+		}()
+	}
+	for _, run := range runs {
+		jobs <- run
+	}
+	close(jobs)
+	workers.Wait()
+	close(errs)
+	return <-errs
+}
+
+// Utilities
+
+// seedStream derives a reproducible sequence of run seeds from a base seed
+type seedStream struct {
+	rng *rand.Rand
+}
+
+// newSeedStream creates a seed stream for the base seed
+func newSeedStream(base int64) *seedStream {
+	return &seedStream{rng: rand.New(rand.NewSource(base))}
+}
+
+// next returns the next non-zero seed
+func (s *seedStream) next() int64 {
+	for {
+		if seed := s.rng.Int63(); seed != 0 {
+			return seed
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+	"goenv/Services"
+	"time"
+)
+
+// Initializations
+
+// ClassConfig is a struct for the configuration of one priority car class
+type ClassConfig struct {
+	Share    float64 `yaml:"share" json:"share"`       // share of arriving cars in the class
+	Priority int     `yaml:"priority" json:"priority"` // served ahead of lower priorities, regular cars have 0
+}
+
+// ClassesConfig is a struct for the configuration of priority car classes, the remaining cars are regular
+type ClassesConfig struct {
+	Emergency ClassConfig `yaml:"emergency" json:"emergency"`
+	Fleet     ClassConfig `yaml:"fleet" json:"fleet"`
+}
+
+// ClassStats is a struct for output construction of the cars of one class
+type ClassStats struct {
+	Class                string `yaml:"class" json:"class"`
+	TotalCars            int    `yaml:"total_cars" json:"total_cars"`
+	AvgStandQueueTime    int    `yaml:"avg_stand_queue_time" json:"avg_stand_queue_time"`
+	MaxStandQueueTime    int    `yaml:"max_stand_queue_time" json:"max_stand_queue_time"`
+	P95StandQueueTime    int    `yaml:"p95_stand_queue_time" json:"p95_stand_queue_time"`
+	AvgRegisterQueueTime int    `yaml:"avg_register_queue_time" json:"avg_register_queue_time"`
+	AvgTotalTime         int    `yaml:"avg_total_time" json:"avg_total_time"`
+}
+
+// Utilities
+
+// classes maps priority car classes to their configuration
+func (classes *ClassesConfig) classes() map[Services.CarClass]*ClassConfig {
+	return map[Services.CarClass]*ClassConfig{
+		Services.Emergency: &classes.Emergency,
+		Services.Fleet:     &classes.Fleet,
+	}
+}
+
+// enabled reports whether any priority class has a share
+func (classes ClassesConfig) enabled() bool {
+	for _, class := range classes.classes() {
+		if class.Share > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// validate checks the shares and priorities of the classes
+func (classes ClassesConfig) validate() error {
+	total := 0.0
+	for _, class := range Services.PriorityClasses {
+		setup := classes.classes()[class]
+		if setup.Share < 0 || setup.Priority < 0 {
+			return fmt.Errorf("classes.%s share and priority must not be negative", class)
+		}
+		total += setup.Share
+	}
+	if total > 1 {
+		return fmt.Errorf("shares of the car classes must not add up to more than 1")
+	}
+	return nil
+}
+
+// setups converts the config to station car classes, nil when no class has a share
+func (classes ClassesConfig) setups() map[Services.CarClass]Services.ClassSetup {
+	if !classes.enabled() {
+		return nil
+	}
+	result := map[Services.CarClass]Services.ClassSetup{Services.Regular: {}}
+	for class, setup := range classes.classes() {
+		result[class] = Services.ClassSetup{Share: setup.Share, Priority: setup.Priority}
+	}
+	return result
+}
+
+// classStats computes the waiting times of every car class with cars, regular cars last
+func (classes ClassesConfig) classStats(cars []*Services.Car) []ClassStats {
+	var stats []ClassStats
+	for _, class := range append(Services.PriorityClasses, Services.Regular) {
+		if class != Services.Regular && classes.classes()[class].Share == 0 {
+			continue
+		}
+		s := ClassStats{Class: string(class)}
+		var standQueue, registerQueue, total time.Duration
+		var standTimes []int
+		for _, car := range cars {
+			if car.Class != class {
+				continue
+			}
+			s.TotalCars++
+			standQueue += car.StandQueueTime
+			registerQueue += car.RegisterQueueTime
+			total += car.TotalTime
+			s.MaxStandQueueTime = max(s.MaxStandQueueTime, int(car.StandQueueTime))
+			standTimes = append(standTimes, int(car.StandQueueTime))
+		}
+		if s.TotalCars != 0 {
+			s.AvgStandQueueTime = int(standQueue) / s.TotalCars
+			s.AvgRegisterQueueTime = int(registerQueue) / s.TotalCars
+			s.AvgTotalTime = int(total) / s.TotalCars
+			s.P95StandQueueTime = percentile(standTimes, 0.95)
+		}
+		stats = append(stats, s)
+	}
+	return stats
+}
+
+// allClassStats returns empty statistics of every car class, e.g. to list the metric names
+func allClassStats() []ClassStats {
+	var stats []ClassStats
+	for _, class := range append(Services.PriorityClasses, Services.Regular) {
+		stats = append(stats, ClassStats{Class: string(class)})
+	}
+	return stats
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"goenv/Services"
+	"strings"
+	"testing"
+)
+
+func TestClassesValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		classes ClassesConfig
+		invalid string
+	}{
+		{"none", ClassesConfig{}, ""},
+		{"both", ClassesConfig{Emergency: ClassConfig{Share: 0.05, Priority: 2}, Fleet: ClassConfig{Share: 0.2, Priority: 1}}, ""},
+		{"everybody", ClassesConfig{Emergency: ClassConfig{Share: 0.4}, Fleet: ClassConfig{Share: 0.6}}, ""},
+		{"negative share", ClassesConfig{Fleet: ClassConfig{Share: -0.1}}, "classes.fleet"},
+		{"negative priority", ClassesConfig{Emergency: ClassConfig{Share: 0.1, Priority: -1}}, "classes.emergency"},
+		{"shares above 1", ClassesConfig{Emergency: ClassConfig{Share: 0.5}, Fleet: ClassConfig{Share: 0.6}}, "add up to more than 1"},
+	}
+	for _, tt := range tests {
+		err := tt.classes.validate()
+		if (err != nil) != (tt.invalid != "") || err != nil && !strings.Contains(err.Error(), tt.invalid) {
+			t.Errorf("%s: validate = %v, want error about %q", tt.name, err, tt.invalid)
+		}
+	}
+}
+
+func TestClassesSetups(t *testing.T) {
+	if setups := (ClassesConfig{}).setups(); setups != nil {
+		t.Errorf("setups without shares = %v, want nil", setups)
+	}
+	setups := ClassesConfig{Fleet: ClassConfig{Share: 0.2, Priority: 1}}.setups()
+	if len(setups) != 3 || setups[Services.Fleet] != (Services.ClassSetup{Share: 0.2, Priority: 1}) ||
+		setups[Services.Regular] != (Services.ClassSetup{}) {
+		t.Errorf("setups = %v, want regular, emergency and fleet", setups)
+	}
+}
+
+func TestClassStats(t *testing.T) {
+	classes := ClassesConfig{Emergency: ClassConfig{Share: 0.1, Priority: 2}}
+	cars := []*Services.Car{
+		{Class: Services.Emergency, StandQueueTime: 2, RegisterQueueTime: 1, TotalTime: 20},
+		{Class: Services.Regular, StandQueueTime: 10, RegisterQueueTime: 3, TotalTime: 30},
+		{Class: Services.Regular, StandQueueTime: 20, RegisterQueueTime: 5, TotalTime: 50},
+		{Class: Services.Fleet, StandQueueTime: 99},
+	}
+	want := []ClassStats{
+		{Class: "emergency", TotalCars: 1, AvgStandQueueTime: 2, MaxStandQueueTime: 2, P95StandQueueTime: 2, AvgRegisterQueueTime: 1, AvgTotalTime: 20},
+		{Class: "regular", TotalCars: 2, AvgStandQueueTime: 15, MaxStandQueueTime: 20, P95StandQueueTime: 20, AvgRegisterQueueTime: 4, AvgTotalTime: 40},
+	}
+	got := classes.classStats(cars)
+	if len(got) != len(want) {
+		t.Fatalf("got %d classes, want %d without fleet", len(got), len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("stats of %s = %+v, want %+v", want[i].Class, got[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,397 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"goenv/Services"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Initializations
+
+// Constants for exit codes
+const (
+	exitOK      = 0
+	exitRuntime = 1
+	exitConfig  = 2
+)
+
+// configError marks errors caused by invalid usage or configuration
+type configError struct {
+	err error
+}
+
+func (e configError) Error() string { return e.err.Error() }
+func (e configError) Unwrap() error { return e.err }
+
+// overrides collects repeated --set flags
+type overrides []string
+
+func (o *overrides) String() string { return strings.Join(*o, ",") }
+func (o *overrides) Set(value string) error {
+	*o = append(*o, value)
+	return nil
+}
+
+// configFlags are the flags shared by all commands reading a config
+type configFlags struct {
+	path      string
+	seed      int64
+	overrides overrides
+}
+
+// register adds the config flags to a flag set
+func (cf *configFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&cf.path, "config", "config.yaml", "path to the yaml configuration")
+	fs.Int64Var(&cf.seed, "seed", 0, "random seed, overrides the config seed when set")
+	fs.Var(&cf.overrides, "set", "override a config value, e.g. stations.gas.count=3 (repeatable)")
+}
+
+// load reads the config, applies overrides and validates it
+func (cf *configFlags) load() (Config, error) {
+	config, err := loadConfigFile(cf.path)
+	if err != nil {
+		return config, configError{err}
+	}
+	for _, assignment := range cf.overrides {
+		if err = config.set(assignment); err != nil {
+			return config, configError{err}
+		}
+	}
+	if cf.seed != 0 {
+		config.Seed = cf.seed
+	}
+	if err = config.validate(); err != nil {
+		return config, configError{fmt.Errorf("invalid config: %v", err)}
+	}
+	return config, nil
+}
+
+// command is a subcommand of the cli
+type command struct {
+	name    string
+	summary string
+	run     func(args []string) error
+}
+
+// commands lists all subcommands, the first one is the default
+var commands = []command{
+	{"run", "run a single simulation (default)", runCommand},
+	{"validate", "check a configuration without running it", validateCommand},
+	{"sweep", "run every combination of config ranges", sweepCommand},
+	{"optimize", "find the cheapest layout meeting a waiting time SLA", optimizeCommand},
+	{"compare", "compare configs with common random numbers and paired intervals", compareCommand},
+	{"theory", "compare with Erlang-C and Allen-Cunneen queueing estimates", theoryCommand},
+	{"serve", "serve the simulation REST API", serveCommand},
+}
+
+// Routines
+
+// runCLI runs the command selected by the arguments and returns the exit code
+func runCLI(args []string) int {
+	selected := commands[0]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		found := false
+		for _, cmd := range commands {
+			if cmd.name == args[0] {
+				selected, found = cmd, true
+			}
+		}
+		if !found {
+			fmt.Fprintf(os.Stderr, "Unknown command %q\n", args[0])
+			printUsage(os.Stderr)
+			return exitConfig
+		}
+		args = args[1:]
+	}
+	err := selected.run(args)
+	switch {
+	case err == nil, errors.Is(err, flag.ErrHelp):
+		return exitOK
+	case errors.As(err, &configError{}):
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitConfig
+	default:
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitRuntime
+	}
+}
+
+// Commands
+
+// runCommand runs a single simulation and writes its statistics
+func runCommand(args []string) error {
+	fs := newFlagSet("run")
+	var cf configFlags
+	cf.register(fs)
+	out := fs.String("out", "final_stats.yaml", "path of the statistics file, - for stdout")
+	format := fs.String("format", "yaml", "comma separated statistics formats: yaml, json, csv, markdown")
+	quiet := fs.Bool("quiet", false, "do not print stand and register messages or the statistics")
+	tui := fs.Bool("tui", false, "show the station state while the simulation runs")
+	report := fs.String("report", "", "path of a self-contained html report of the run")
+	metrics := fs.String("metrics", "", "address serving prometheus metrics while the simulation runs, e.g. :9090")
+	replications := fs.Int("replications", 1, "number of independent replications with derived seeds")
+	parallel := fs.Int("parallel", runtime.NumCPU(), "number of replications running at once")
+	target := fs.String("target", "", "keep replicating until a metric's 95% CI half-width is below a value, e.g. gas.avg_queue_time=2")
+	maxReplications := fs.Int("max-replications", 100, "upper bound on replications when using --target")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	config, err := cf.load()
+	if err != nil {
+		return err
+	}
+	formats, err := parseFormats(*format)
+	if err != nil {
+		return configError{err}
+	}
+	if *replications > 1 || *target != "" {
+		if *tui || *report != "" || *metrics != "" || config.Events.File != "" || config.TimeSeries.File != "" {
+			return configError{fmt.Errorf("replications cannot be combined with --tui, --report, --metrics, an event log or a time series")}
+		}
+		options := replicationOptions{count: *replications, parallel: *parallel, max: *maxReplications, progress: os.Stderr}
+		if *quiet {
+			options.progress = io.Discard
+		}
+		if *target != "" {
+			name, value, found := strings.Cut(*target, "=")
+			halfWidth, err := strconv.ParseFloat(value, 64)
+			if !found || err != nil || !isMetric(name) || halfWidth <= 0 {
+				return configError{fmt.Errorf("target %q is not a known metric=half-width pair", *target)}
+			}
+			options.targetMetric, options.targetHalfWidth = name, halfWidth
+			// A half-width needs at least two replications to start from
+			options.count = max(options.count, 2)
+		}
+		if options.count < 2 || options.parallel < 1 {
+			return configError{fmt.Errorf("at least 2 replications and 1 parallel run are needed")}
+		}
+		return replicateCommand(config, options, formats, *out, *quiet)
+	}
+
+	station, err := config.newStation()
+	if err != nil {
+		return configError{err}
+	}
+	if !*quiet {
+		warnUnstable(os.Stderr, estimateQueues(queueModels(config, station)))
+	}
+	if *quiet || *tui {
+		station.Log = io.Discard
+	}
+	options := runOptions{tui: *tui, metricsAddr: *metrics}
+	if *report != "" {
+		options.report = newReportData(newSampler(config.TimeSeries.Interval))
+	}
+	results, err := runStation(station, config, options)
+	if err != nil {
+		return err
+	}
+	if *report != "" {
+		if err = writeReport(*report, config, results, options.report); err != nil {
+			return fmt.Errorf("error writing report: %v", err)
+		}
+	}
+
+	for i, format := range formats {
+		encoded, err := format.encode(results)
+		if err != nil {
+			return fmt.Errorf("error encoding statistics as %s: %v", format.name, err)
+		}
+		if *out == "-" {
+			if _, err = os.Stdout.Write(encoded); err != nil {
+				return err
+			}
+			continue
+		}
+		path := outputPath(*out, format, len(formats) > 1)
+		if err = os.WriteFile(path, encoded, 0644); err != nil {
+			return fmt.Errorf("error writing statistics: %v", err)
+		}
+		if !*quiet && i == 0 {
+			fmt.Printf("Final statistics:\n%s\n", string(encoded))
+		}
+	}
+	return nil
+}
+
+// replicateCommand runs replications and writes the summary of every metric
+func replicateCommand(config Config, options replicationOptions, formats []outputFormat, out string, quiet bool) error {
+	start := time.Now()
+	stats, base, err := runReplications(config, options)
+	if err != nil {
+		return err
+	}
+	summaries := summarize(stats)
+	summary := summaryTable(summaries, newMetadata(config, base, start, time.Now()))
+	for _, format := range formats {
+		if out == "-" {
+			if err = writeTable(summary, format.name, out); err != nil {
+				return err
+			}
+			continue
+		}
+		if err = writeTable(summary, format.name, outputPath(out, format, len(formats) > 1)); err != nil {
+			return err
+		}
+	}
+	if !quiet && out != "-" {
+		encoded, _ := summary.encode("markdown")
+		fmt.Printf("Summary of %d replications:\n%s\n", len(stats), encoded)
+	}
+	return nil
+}
+
+// validateCommand checks a configuration and reports the first problem found
+func validateCommand(args []string) error {
+	fs := newFlagSet("validate")
+	var cf configFlags
+	cf.register(fs)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	config, err := cf.load()
+	if err != nil {
+		return err
+	}
+	// Loading the trace checks it against the stands
+	station, err := config.newStation()
+	if err != nil {
+		return configError{err}
+	}
+	warnUnstable(os.Stdout, estimateQueues(queueModels(config, station)))
+	fmt.Printf("%s is valid\n", cf.path)
+	return nil
+}
+
+// serveCommand serves the simulation api
+func serveCommand(args []string) error {
+	fs := newFlagSet("serve")
+	addr := fs.String("addr", ":8080", "address to listen on")
+	workers := fs.Int("workers", 2, "number of simulations running at once")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *workers < 1 {
+		return configError{fmt.Errorf("workers must be at least 1")}
+	}
+	return serve(*addr, *workers)
+}
+
+// Utilities
+
+// runOptions selects what is observed while a station runs
+type runOptions struct {
+	tui         bool        // show the dashboard
+	report      *reportData // collect data for the html report
+	metricsAddr string      // serve prometheus metrics on this address
+}
+
+// runStation runs a configured station with its event log and optional observers
+func runStation(station *Services.Station, config Config, options runOptions) (Results, error) {
+	// Event log routine
+	var eventLog sync.WaitGroup
+	if config.Events.File != "" {
+		file, err := os.Create(config.Events.File)
+		if err != nil {
+			return Results{}, fmt.Errorf("error creating event log file: %v", err)
+		}
+		defer file.Close()
+		station.Events = make(chan Services.Event, 100)
+		eventLog.Add(1)
+		go Services.EventLogRoutine(station.Events, file, config.eventFormat(), eventLog.Done)
+	}
+	// Metrics server
+	var metrics *metricsRegistry
+	if options.metricsAddr != "" {
+		listener, err := net.Listen("tcp", options.metricsAddr)
+		if err != nil {
+			return Results{}, fmt.Errorf("error serving metrics: %v", err)
+		}
+		metrics = newMetricsRegistry()
+		mux := http.NewServeMux()
+		mux.Handle("GET /metrics", metrics)
+		server := &http.Server{Handler: mux}
+		defer server.Close()
+		go server.Serve(listener)
+	}
+	start := time.Now()
+	station.Open()
+	// Observer routines
+	var observers sync.WaitGroup
+	stop := make(chan struct{})
+	var exit <-chan *Services.Car = station.Exit
+	if options.tui {
+		observers.Add(1)
+		go dashboardRoutine(station, os.Stdout, stop, observers.Done)
+	}
+	if metrics != nil {
+		runMetrics := newRunMetrics(station)
+		metrics.add("", runMetrics)
+		exit = runMetrics.collectCars(exit)
+	}
+	// The report and the time series share their samples
+	var samples *sampler
+	if options.report != nil {
+		samples = options.report.samples
+		exit = options.report.collectCars(exit)
+	}
+	if samples == nil && config.TimeSeries.File != "" {
+		samples = newSampler(config.TimeSeries.Interval)
+	}
+	if samples != nil {
+		observers.Add(1)
+		go samples.samplingRoutine(station, stop, observers.Done)
+	}
+	stats := aggregate(station, exit, config)
+	close(stop)
+	observers.Wait()
+	eventLog.Wait()
+	if config.TimeSeries.File != "" {
+		if err := writeTable(seriesTable(samples.snapshots()), config.seriesFormat(), config.TimeSeries.File); err != nil {
+			return Results{}, err
+		}
+	}
+	return Results{Metadata: newMetadata(config, station.Seed, start, time.Now()), FinalStats: stats}, nil
+}
+
+// newFlagSet creates a flag set printing the command usage on errors
+func newFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s %s [flags]\n", os.Args[0], name)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// parseFlags parses command flags, rejecting unexpected positional arguments
+func parseFlags(fs *flag.FlagSet, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+		return configError{err}
+	}
+	if fs.NArg() > 0 {
+		return configError{fmt.Errorf("unexpected arguments: %s", strings.Join(fs.Args(), " "))}
+	}
+	return nil
+}
+
+// printUsage lists the available commands
+func printUsage(w io.Writer) {
+	fmt.Fprintf(w, "Usage: %s [command] [flags]\n\nCommands:\n", os.Args[0])
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-10s %s\n", cmd.name, cmd.summary)
+	}
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// Initializations
+
+// scenario is a named config taking part in a comparison
+type scenario struct {
+	name   string
+	config Config
+	stats  []FinalStats
+}
+
+// difference is the paired estimate of a metric difference between a scenario and the baseline
+type difference struct {
+	metric    string
+	baseline  float64
+	scenario  float64
+	mean      float64
+	halfWidth float64
+	winner    string
+}
+
+// Commands
+
+// compareCommand runs configs with common random numbers and compares them metric by metric
+func compareCommand(args []string) error {
+	fs := newFlagSet("compare")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s compare [flags] baseline.yaml scenario.yaml...\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	seed := fs.Int64("seed", 0, "base seed shared by all configs, overrides the config seeds when set")
+	var sets overrides
+	fs.Var(&sets, "set", "override a value of every config, e.g. cars.count=500 (repeatable)")
+	replications := fs.Int("replications", 10, "number of paired replications of every config")
+	parallel := fs.Int("parallel", runtime.NumCPU(), "number of replications running at once")
+	out := fs.String("out", "-", "path of the comparison table, - for stdout")
+	format := fs.String("format", "markdown", "table format: csv, markdown, json or yaml")
+	quiet := fs.Bool("quiet", false, "do not print progress and verdicts")
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+		return configError{err}
+	}
+	if fs.NArg() < 2 {
+		return configError{fmt.Errorf("compare needs at least two configs")}
+	}
+	if *replications < 2 || *parallel < 1 {
+		return configError{fmt.Errorf("compare needs at least 2 replications and 1 parallel run")}
+	}
+	if _, err := (table{}).encode(*format); err != nil {
+		return configError{err}
+	}
+	// The same seed for every config gives all of them the same cars
+	base := *seed
+	if base == 0 {
+		base = time.Now().UnixNano()
+	}
+	var scenarios []*scenario
+	for _, path := range fs.Args() {
+		cf := configFlags{path: path, seed: base, overrides: sets}
+		config, err := cf.load()
+		if err != nil {
+			return err
+		}
+		scenarios = append(scenarios, &scenario{name: scenarioName(path, scenarios), config: config})
+	}
+	progress := io.Writer(os.Stderr)
+	if *quiet {
+		progress = io.Discard
+	}
+
+	for _, s := range scenarios {
+		fmt.Fprintf(progress, "Running %d replications of %s\n", *replications, s.name)
+		options := replicationOptions{count: *replications, parallel: *parallel, progress: io.Discard}
+		stats, _, err := runReplications(s.config, options)
+		if err != nil {
+			return err
+		}
+		s.stats = stats
+	}
+
+	t := table{columns: []string{"metric", "baseline", "scenario", "baseline_mean", "scenario_mean",
+		"difference", "ci_low", "ci_high", "winner", "replications", "base_seed"}}
+	baseline := scenarios[0]
+	for _, s := range scenarios[1:] {
+		wins := make(map[string]int)
+		for _, d := range compareStats(baseline, s) {
+			t.addRow(d.metric, baseline.name, s.name, d.baseline, d.scenario,
+				d.mean, d.mean-d.halfWidth, d.mean+d.halfWidth, d.winner, *replications, base)
+			wins[d.winner]++
+		}
+		fmt.Fprintf(progress, "%s vs %s: %s better on %d metrics, %s better on %d, no significant difference on %d\n",
+			s.name, baseline.name, s.name, wins[s.name], baseline.name, wins[baseline.name], wins["none"])
+	}
+	return writeTable(t, *format, *out)
+}
+
+// Utilities
+
+// compareStats estimates the paired differences of the scenario from the baseline
+//
+// Replication i of both configs shares its seed, so differences are taken
+// per replication and a metric is won only when its 95% interval excludes zero.
+// Metrics missing from either config, such as economics, are skipped.
+func compareStats(baseline, s *scenario) []difference {
+	var differences []difference
+	for i, m := range baseline.stats[0].metrics() {
+		// Car counts follow from the arrivals, not from the layout
+		if strings.HasSuffix(m.name, ".total_cars") {
+			continue
+		}
+		j := metricIndex(s.stats[0].metrics(), m.name)
+		if j < 0 {
+			continue
+		}
+		var baseValues, values, deltas []float64
+		for r := range baseline.stats {
+			b, v := baseline.stats[r].metrics()[i].value, s.stats[r].metrics()[j].value
+			baseValues = append(baseValues, b)
+			values = append(values, v)
+			deltas = append(deltas, v-b)
+		}
+		d := difference{metric: m.name, winner: "none"}
+		d.baseline, _, _ = confidenceInterval(baseValues)
+		d.scenario, _, _ = confidenceInterval(values)
+		d.mean, _, d.halfWidth = confidenceInterval(deltas)
+		better, worse := s.name, baseline.name
+		if higherIsBetter(m.name) {
+			better, worse = worse, better
+		}
+		if d.mean+d.halfWidth < 0 {
+			d.winner = better
+		} else if d.mean-d.halfWidth > 0 {
+			d.winner = worse
+		}
+		differences = append(differences, d)
+	}
+	return differences
+}
+
+// scenarioName names a config after its file, keeping names unique
+func scenarioName(path string, scenarios []*scenario) string {
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	for _, s := range scenarios {
+		if s.name == name {
+			return fmt.Sprintf("%s#%d", name, len(scenarios)+1)
+		}
+	}
+	return name
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestCompareStats(t *testing.T) {
+	baseline := &scenario{name: "base"}
+	candidate := &scenario{name: "more-stands"}
+	gas := [][2]int{{10, 5}, {12, 6}, {14, 7}}
+	diesel := [][2]int{{10, 11}, {10, 9}, {10, 10}}
+	registers := [][2]int{{1, 3}, {1, 3}, {1, 4}}
+	for r := range gas {
+		baseline.stats = append(baseline.stats, FinalStats{
+			Gas:       StationStats{TotalCars: 10, AvgQueueTime: gas[r][0]},
+			Diesel:    StationStats{TotalCars: 10, AvgQueueTime: diesel[r][0]},
+			Registers: StationStats{TotalCars: 20, AvgQueueTime: registers[r][0]},
+			Economics: &Economics{Profit: 100},
+		})
+		candidate.stats = append(candidate.stats, FinalStats{
+			Gas:       StationStats{TotalCars: 12, AvgQueueTime: gas[r][1]},
+			Diesel:    StationStats{TotalCars: 10, AvgQueueTime: diesel[r][1]},
+			Registers: StationStats{TotalCars: 22, AvgQueueTime: registers[r][1]},
+			Economics: &Economics{Profit: float64(150 + 10*r)},
+		})
+	}
+	differences := make(map[string]difference)
+	for _, d := range compareStats(baseline, candidate) {
+		differences[d.metric] = d
+	}
+	tests := []struct {
+		metric             string
+		baseline, scenario float64
+		mean               float64
+		winner             string
+	}{
+		{"gas.avg_queue_time", 12, 6, -6, "more-stands"},
+		{"diesel.avg_queue_time", 10, 10, 0, "none"},
+		{"registers.avg_queue_time", 1, 10.0 / 3, 7.0 / 3, "base"},
+		{"economics.profit", 100, 160, 60, "more-stands"},
+		{"lpg.avg_queue_time", 0, 0, 0, "none"},
+	}
+	for _, tt := range tests {
+		d, ok := differences[tt.metric]
+		if !ok {
+			t.Errorf("%s: no difference", tt.metric)
+			continue
+		}
+		if !near(d.baseline, tt.baseline) || !near(d.scenario, tt.scenario) || !near(d.mean, tt.mean) || d.winner != tt.winner {
+			t.Errorf("%s: %v -> %v by %v won by %s, want %v -> %v by %v won by %s", tt.metric,
+				d.baseline, d.scenario, d.mean, d.winner, tt.baseline, tt.scenario, tt.mean, tt.winner)
+		}
+	}
+	if _, ok := differences["gas.total_cars"]; ok {
+		t.Errorf("car counts were compared")
+	}
+
+	// Metrics of only one config are skipped
+	for i := range candidate.stats {
+		candidate.stats[i].Economics = nil
+	}
+	for _, d := range compareStats(baseline, candidate) {
+		if d.metric == "economics.profit" {
+			t.Errorf("economics compared against a config without economics")
+		}
+	}
+}
+
+func TestHigherIsBetter(t *testing.T) {
+	tests := []struct {
+		metric string
+		want   bool
+	}{
+		{"gas.avg_queue_time", false},
+		{"economics.cost", false},
+		{"economics.lost_revenue", false},
+		{"economics.revenue", true},
+		{"economics.profit", true},
+	}
+	for _, tt := range tests {
+		if got := higherIsBetter(tt.metric); got != tt.want {
+			t.Errorf("higherIsBetter(%s) = %v, want %v", tt.metric, got, tt.want)
+		}
+	}
+}
+
+func TestScenarioName(t *testing.T) {
+	scenarios := []*scenario{{name: "base"}, {name: "wide"}}
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"configs/rush.yaml", "rush"},
+		{"other/base.yaml", "base#3"},
+		{"wide", "wide#3"},
+	}
+	for _, tt := range tests {
+		if got := scenarioName(tt.path, scenarios); got != tt.want {
+			t.Errorf("scenarioName(%s) = %s, want %s", tt.path, got, tt.want)
+		}
+	}
+}
+
+func near(got, want float64) bool {
+	return got-want < 1e-9 && want-got < 1e-9
+}
@@ -0,0 +1,416 @@
+package main
+
+import (
+	"fmt"
+	"goenv/Services"
+	"gopkg.in/yaml.v2"
+	"os"
+	"strings"
+)
+
+// Initializations
+
+// StandConfig is a struct for the configuration of one fuel type
+type StandConfig struct {
+	Count        int `yaml:"count" json:"count"`
+	ServeTimeMin int `yaml:"serve_time_min" json:"serve_time_min"`
+	ServeTimeMax int `yaml:"serve_time_max" json:"serve_time_max"`
+}
+
+// RegisterClassConfig is a struct for the configuration of self-service registers
+type RegisterClassConfig struct {
+	Count         int      `yaml:"count" json:"count"`
+	HandleTimeMin int      `yaml:"handle_time_min" json:"handle_time_min"`
+	HandleTimeMax int      `yaml:"handle_time_max" json:"handle_time_max"`
+	Preference    float64  `yaml:"preference" json:"preference"` // share of cars preferring the class
+	MaxBasket     float64  `yaml:"max_basket" json:"max_basket"` // larger baskets are not eligible, no limit when zero
+	Fuels         []string `yaml:"fuels" json:"fuels"`           // eligible fuel types, all when empty
+	Methods       []string `yaml:"methods" json:"methods"`       // eligible payment methods, all when empty
+}
+
+// Config is a struct for program configuration
+type Config struct {
+	Seed int64 `yaml:"seed" json:"seed"`
+	Cars struct {
+		Count          int `yaml:"count" json:"count"`
+		ArrivalTimeMin int `yaml:"arrival_time_min" json:"arrival_time_min"`
+		ArrivalTimeMax int `yaml:"arrival_time_max" json:"arrival_time_max"`
+		Trace          struct {
+			File      string  `yaml:"file" json:"file"`
+			TimeScale float64 `yaml:"time_scale" json:"time_scale"`
+		} `yaml:"trace" json:"trace"`
+	} `yaml:"cars" json:"cars"`
+	Stations struct {
+		Gas      StandConfig `yaml:"gas" json:"gas"`
+		Diesel   StandConfig `yaml:"diesel" json:"diesel"`
+		Lpg      StandConfig `yaml:"lpg" json:"lpg"`
+		Electric StandConfig `yaml:"electric" json:"electric"`
+	} `yaml:"stations" json:"stations"`
+	Dispensers []DispenserConfig `yaml:"dispensers" json:"dispensers"`
+	Registers  struct {
+		Count         int                 `yaml:"count" json:"count"`
+		HandleTimeMin int                 `yaml:"handle_time_min" json:"handle_time_min"`
+		HandleTimeMax int                 `yaml:"handle_time_max" json:"handle_time_max"`
+		Kiosk         RegisterClassConfig `yaml:"kiosk" json:"kiosk"`
+		Mobile        RegisterClassConfig `yaml:"mobile" json:"mobile"`
+	} `yaml:"registers" json:"registers"`
+	Classes   ClassesConfig   `yaml:"classes" json:"classes"`
+	Payments  PaymentsConfig  `yaml:"payments" json:"payments"`
+	Layout    LayoutConfig    `yaml:"layout" json:"layout"`
+	Wash      WashConfig      `yaml:"wash" json:"wash"`
+	Pipeline  []string        `yaml:"pipeline" json:"pipeline"` // order of stages, fuel, payment and wash
+	Warmup    WarmupConfig    `yaml:"warmup" json:"warmup"`
+	Economics EconomicsConfig `yaml:"economics" json:"economics"`
+	Pricing   PricingConfig   `yaml:"pricing" json:"pricing"`
+	Staffing  StaffingConfig  `yaml:"staffing" json:"staffing"`
+	Events    struct {
+		File   string `yaml:"file" json:"file"`
+		Format string `yaml:"format" json:"format"`
+	} `yaml:"events" json:"events"`
+	TimeSeries struct {
+		File     string `yaml:"file" json:"file"`
+		Format   string `yaml:"format" json:"format"`
+		Interval int    `yaml:"interval" json:"interval"` // ms between samples
+	} `yaml:"timeseries" json:"timeseries"`
+}
+
+// defaultConfig returns the configuration matching the default station setup
+func defaultConfig() Config {
+	var config Config
+	station := Services.NewStation()
+	config.Cars.Count = station.CarNum
+	config.Cars.ArrivalTimeMin = station.StaggerMin
+	config.Cars.ArrivalTimeMax = station.StaggerMax
+	config.Cars.Trace.TimeScale = station.TraceScale
+	for fuel, stand := range config.stands() {
+		setup := station.Fuels[fuel]
+		*stand = StandConfig{Count: setup.Count, ServeTimeMin: setup.MinT, ServeTimeMax: setup.MaxT}
+	}
+	config.Registers.Count = station.NumRegisters
+	config.Registers.HandleTimeMin = station.MinPaymentT
+	config.Registers.HandleTimeMax = station.MaxPaymentT
+	config.TimeSeries.Interval = 10
+	config.Economics.Hour = 60
+	return config
+}
+
+// loadConfigFile loads configuration from a yaml file over the defaults
+func loadConfigFile(path string) (Config, error) {
+	config := defaultConfig()
+	file, err := os.ReadFile(path)
+	if err != nil {
+		return config, fmt.Errorf("error reading %s file: %v", path, err)
+	}
+	if err = yaml.Unmarshal(file, &config); err != nil {
+		return config, fmt.Errorf("error unmarshalling %s file: %v", path, err)
+	}
+	return config, nil
+}
+
+// set overrides a single value addressed by a dotted path, e.g. stations.gas.count=3
+func (config *Config) set(assignment string) error {
+	path, raw, found := strings.Cut(assignment, "=")
+	if !found || path == "" {
+		return fmt.Errorf("override %q is not in key=value form", assignment)
+	}
+	var value interface{}
+	if err := yaml.Unmarshal([]byte(raw), &value); err != nil {
+		return fmt.Errorf("override %q has an invalid value: %v", assignment, err)
+	}
+	// Nesting the value under its path and decoding it over the config
+	keys := strings.Split(path, ".")
+	for i := len(keys) - 1; i >= 0; i-- {
+		value = map[string]interface{}{keys[i]: value}
+	}
+	override, err := yaml.Marshal(value)
+	if err != nil {
+		return err
+	}
+	if err = yaml.UnmarshalStrict(override, config); err != nil {
+		return fmt.Errorf("override %q does not match the config: %v", assignment, err)
+	}
+	return nil
+}
+
+// stands maps fuel types to their stand configuration
+func (config *Config) stands() map[Services.FuelType]*StandConfig {
+	return map[Services.FuelType]*StandConfig{
+		Services.Gas:      &config.Stations.Gas,
+		Services.Diesel:   &config.Stations.Diesel,
+		Services.LPG:      &config.Stations.Lpg,
+		Services.Electric: &config.Stations.Electric,
+	}
+}
+
+// standCount returns the number of stands selling the fuel, the island positions with a layout
+func (config *Config) standCount(fuel Services.FuelType) int {
+	if config.Layout.enabled() {
+		return config.Layout.positions(fuel)
+	}
+	count := config.stands()[fuel].Count
+	for _, dispenser := range config.Dispensers {
+		if dispenser.sells(fuel) {
+			count += dispenser.Count
+		}
+	}
+	return count
+}
+
+// totalStands returns the number of stands, multi-product ones counted once
+func (config *Config) totalStands() int {
+	total := 0
+	for _, fuel := range Services.FuelTypes {
+		if config.Layout.enabled() {
+			total += config.Layout.positions(fuel)
+		} else {
+			total += config.stands()[fuel].Count
+		}
+	}
+	for _, dispenser := range config.Dispensers {
+		if !config.Layout.enabled() {
+			total += dispenser.Count
+		}
+	}
+	return total
+}
+
+// multiProduct reports whether any multi-product stands are configured
+func (config *Config) multiProduct() bool {
+	for _, dispenser := range config.Dispensers {
+		if dispenser.Count > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// registerClasses returns the configuration of every self-service register class
+func (config *Config) registerClasses() map[Services.RegisterClass]*RegisterClassConfig {
+	return map[Services.RegisterClass]*RegisterClassConfig{
+		Services.Kiosk:  &config.Registers.Kiosk,
+		Services.Mobile: &config.Registers.Mobile,
+	}
+}
+
+// selfService reports whether any self-service registers are configured
+func (config *Config) selfService() bool {
+	for _, class := range config.registerClasses() {
+		if class.Count > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// eventFormat returns the event log format, guessed from the file name if not set
+func (config *Config) eventFormat() string {
+	if config.Events.Format != "" {
+		return config.Events.Format
+	}
+	if strings.HasSuffix(config.Events.File, ".csv") {
+		return "csv"
+	}
+	return "jsonl"
+}
+
+// seriesFormat returns the time series format, guessed from the file name if not set
+func (config *Config) seriesFormat() string {
+	if config.TimeSeries.Format != "" {
+		return config.TimeSeries.Format
+	}
+	if strings.HasSuffix(config.TimeSeries.File, ".json") {
+		return "json"
+	}
+	return "csv"
+}
+
+// validate checks the configuration for values the simulation cannot run with
+func (config *Config) validate() error {
+	if config.Cars.Count < 0 {
+		return fmt.Errorf("cars.count must not be negative")
+	}
+	if err := checkRange("cars.arrival_time", config.Cars.ArrivalTimeMin, config.Cars.ArrivalTimeMax); err != nil {
+		return err
+	}
+	if config.Cars.Trace.TimeScale < 0 {
+		return fmt.Errorf("cars.trace.time_scale must not be negative")
+	}
+	for _, fuel := range Services.FuelTypes {
+		stand := config.stands()[fuel]
+		name := "stations." + strings.ToLower(string(fuel))
+		if config.standCount(fuel) < 1 && config.Cars.Trace.File == "" {
+			if config.Layout.enabled() {
+				return fmt.Errorf("layout.islands need an island selling %s", strings.ToLower(string(fuel)))
+			}
+			return fmt.Errorf("%s.count must be at least 1", name)
+		}
+		if err := checkRange(name+".serve_time", stand.ServeTimeMin, stand.ServeTimeMax); err != nil {
+			return err
+		}
+	}
+	if err := config.Layout.validate(); err != nil {
+		return err
+	}
+	for i, dispenser := range config.Dispensers {
+		if err := dispenser.validate(i); err != nil {
+			return err
+		}
+	}
+	if config.Layout.enabled() && config.multiProduct() {
+		return fmt.Errorf("dispensers cannot be combined with layout.islands")
+	}
+	if err := config.Wash.validate(); err != nil {
+		return err
+	}
+	if err := config.validatePipeline(); err != nil {
+		return err
+	}
+	if config.Registers.Count < 1 {
+		return fmt.Errorf("registers.count must be at least 1")
+	}
+	if err := checkRange("registers.handle_time", config.Registers.HandleTimeMin, config.Registers.HandleTimeMax); err != nil {
+		return err
+	}
+	preference := 0.0
+	for _, class := range Services.SelfServiceClasses {
+		setup := config.registerClasses()[class]
+		name := "registers." + string(class)
+		if setup.Count < 0 || setup.Preference < 0 || setup.MaxBasket < 0 {
+			return fmt.Errorf("%s count, preference and max_basket must not be negative", name)
+		}
+		if setup.Count == 0 {
+			continue
+		}
+		if err := checkRange(name+".handle_time", setup.HandleTimeMin, setup.HandleTimeMax); err != nil {
+			return err
+		}
+		for _, fuel := range setup.Fuels {
+			if _, err := Services.ParseFuelType(fuel); err != nil {
+				return fmt.Errorf("%s.fuels: %v", name, err)
+			}
+		}
+		for _, method := range setup.Methods {
+			if _, err := Services.ParsePaymentMethod(method); err != nil {
+				return fmt.Errorf("%s.methods: %v", name, err)
+			}
+		}
+		preference += setup.Preference
+	}
+	if preference > 1 {
+		return fmt.Errorf("preferences of the self-service registers must not add up to more than 1")
+	}
+	if err := config.Classes.validate(); err != nil {
+		return err
+	}
+	if err := config.Payments.validate(); err != nil {
+		return err
+	}
+	if config.Warmup.Cars < 0 || config.Warmup.Time < 0 {
+		return fmt.Errorf("warmup.cars and warmup.time must not be negative")
+	}
+	if (config.Warmup.Cars > 0 && config.Warmup.Time > 0) || (config.Warmup.Auto && config.Warmup.Cars+config.Warmup.Time > 0) {
+		return fmt.Errorf("only one of warmup.cars, warmup.time and warmup.auto can be set")
+	}
+	switch config.eventFormat() {
+	case "jsonl", "csv":
+	default:
+		return fmt.Errorf("events.format must be jsonl or csv")
+	}
+	if err := config.Economics.validate(); err != nil {
+		return err
+	}
+	if err := config.Pricing.validate(); err != nil {
+		return err
+	}
+	if err := config.Staffing.validate(config.Registers.Count); err != nil {
+		return err
+	}
+	if config.Pricing.Strategy != "" && !config.Economics.enabled() {
+		return fmt.Errorf("pricing.strategy needs the base prices of economics.fuels")
+	}
+	switch config.seriesFormat() {
+	case "csv", "json":
+	default:
+		return fmt.Errorf("timeseries.format must be csv or json")
+	}
+	if config.TimeSeries.Interval < 1 {
+		return fmt.Errorf("timeseries.interval must be at least 1")
+	}
+	return nil
+}
+
+// checkRange checks that a min/max time pair describes a valid random range
+func checkRange(name string, min, max int) error {
+	if min < 0 || max <= min {
+		return fmt.Errorf("%s_min must be non-negative and lower than %s_max", name, name)
+	}
+	return nil
+}
+
+// newStation creates a station configured by the config
+func (config *Config) newStation() (*Services.Station, error) {
+	station := Services.NewStation()
+	station.Seed = config.Seed
+	station.StaggerMin = config.Cars.ArrivalTimeMin
+	station.StaggerMax = config.Cars.ArrivalTimeMax
+	station.CarNum = config.Cars.Count
+	for fuel, stand := range config.stands() {
+		pricing := config.Economics.pricing()[fuel]
+		station.Fuels[fuel] = Services.FuelSetup{Count: stand.Count, MinT: stand.ServeTimeMin, MaxT: stand.ServeTimeMax,
+			MinVolume: pricing.VolumeMin, MaxVolume: pricing.VolumeMax}
+	}
+	station.Islands = config.Layout.islands()
+	station.Wash = config.Wash.setup()
+	station.Pipeline = config.pipeline()
+	for _, dispenser := range config.Dispensers {
+		station.Dispensers = append(station.Dispensers, dispenser.setup())
+	}
+	station.MinShopBasket = config.Economics.Shop.BasketMin
+	station.MaxShopBasket = config.Economics.Shop.BasketMax
+	station.Pricer = newPricer(*config, station)
+	station.Staffing = config.Staffing.staffing()
+	station.NumRegisters = config.Registers.Count
+	station.MinPaymentT = config.Registers.HandleTimeMin
+	station.MaxPaymentT = config.Registers.HandleTimeMax
+	station.Classes = config.Classes.setups()
+	station.Payments = config.Payments.setups()
+	station.RegisterClasses = make(map[Services.RegisterClass]Services.RegisterSetup)
+	for class, setup := range config.registerClasses() {
+		registers := Services.RegisterSetup{Count: setup.Count, MinT: setup.HandleTimeMin, MaxT: setup.HandleTimeMax,
+			Preference: setup.Preference, MaxBasket: setup.MaxBasket}
+		for _, name := range setup.Fuels {
+			fuel, _ := Services.ParseFuelType(name)
+			registers.Fuels = append(registers.Fuels, fuel)
+		}
+		for _, name := range setup.Methods {
+			method, _ := Services.ParsePaymentMethod(name)
+			registers.Methods = append(registers.Methods, method)
+		}
+		station.RegisterClasses[class] = registers
+	}
+	// Loads recorded arrivals if a trace file is set
+	if config.Cars.Trace.File != "" {
+		trace, err := Services.LoadTrace(config.Cars.Trace.File)
+		if err != nil {
+			return nil, fmt.Errorf("error reading trace file %s: %v", config.Cars.Trace.File, err)
+		}
+		if err = config.checkTrace(trace); err != nil {
+			return nil, err
+		}
+		station.Trace = trace
+		if config.Cars.Trace.TimeScale > 0 {
+			station.TraceScale = config.Cars.Trace.TimeScale
+		}
+	}
+	return station, nil
+}
+
+// checkTrace checks that every recorded fuel type has a stand to go to
+func (config *Config) checkTrace(trace []Services.TraceRecord) error {
+	for _, record := range trace {
+		if config.standCount(record.Fuel) < 1 {
+			return fmt.Errorf("trace contains %s cars but there are no %s stands", record.Fuel, record.Fuel)
+		}
+	}
+	return nil
+}
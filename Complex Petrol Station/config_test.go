@@ -0,0 +1,140 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestConfigSet(t *testing.T) {
+	tests := []struct {
+		assignment string
+		check      func(Config) bool
+		valid      bool
+	}{
+		{"stations.gas.count=5", func(c Config) bool { return c.Stations.Gas.Count == 5 }, true},
+		{"cars.trace.time_scale=2.5", func(c Config) bool { return c.Cars.Trace.TimeScale == 2.5 }, true},
+		{"warmup.auto=true", func(c Config) bool { return c.Warmup.Auto }, true},
+		{"events.file=events.csv", func(c Config) bool { return c.Events.File == "events.csv" }, true},
+		{"dispensers=[{count: 1, fuels: [gas, lpg]}]", func(c Config) bool {
+			return len(c.Dispensers) == 1 && c.Dispensers[0].Fuels[1] == "lpg"
+		}, true},
+		{"stations.gas.count", nil, false},
+		{"=3", nil, false},
+		{"stations.hydrogen.count=1", nil, false},
+		{"stations.gas.count=many", nil, false},
+		{"cars.count=[1", nil, false},
+	}
+	for _, tt := range tests {
+		config := defaultConfig()
+		err := config.set(tt.assignment)
+		if (err == nil) != tt.valid {
+			t.Errorf("set(%q) error = %v, want valid %v", tt.assignment, err, tt.valid)
+			continue
+		}
+		if tt.valid && !tt.check(config) {
+			t.Errorf("set(%q) did not change the config", tt.assignment)
+		}
+	}
+}
+
+func TestConfigSetKeepsOtherValues(t *testing.T) {
+	config := defaultConfig()
+	if err := config.set("stations.gas.serve_time_max=9"); err != nil {
+		t.Fatal(err)
+	}
+	want := defaultConfig().Stations.Gas
+	want.ServeTimeMax = 9
+	if config.Stations.Gas != want || config.Registers.Count != defaultConfig().Registers.Count {
+		t.Errorf("gas = %+v, want only serve_time_max changed from %+v", config.Stations.Gas, defaultConfig().Stations.Gas)
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(*Config)
+		err    string // part of the error, valid when empty
+	}{
+		{"defaults", func(c *Config) {}, ""},
+		{"negative cars", func(c *Config) { c.Cars.Count = -1 }, "cars.count"},
+		{"empty arrival range", func(c *Config) { c.Cars.ArrivalTimeMax = c.Cars.ArrivalTimeMin }, "cars.arrival_time_min"},
+		{"no gas stand", func(c *Config) { c.Stations.Gas.Count = 0 }, "stations.gas.count"},
+		{"no gas stand with a trace", func(c *Config) { c.Stations.Gas.Count = 0; c.Cars.Trace.File = "trace.csv" }, ""},
+		{"negative serve time", func(c *Config) { c.Stations.Lpg.ServeTimeMin = -1 }, "stations.lpg.serve_time_min"},
+		{"no register", func(c *Config) { c.Registers.Count = 0 }, "registers.count"},
+		{"event format", func(c *Config) { c.Events.Format = "xml" }, "events.format"},
+		{"two warm-ups", func(c *Config) { c.Warmup.Cars, c.Warmup.Time = 5, 10 }, "only one of warmup"},
+		{"series interval", func(c *Config) { c.TimeSeries.Interval = 0 }, "timeseries.interval"},
+		{"pricing without prices", func(c *Config) { c.Pricing.Strategy = "fixed" }, "economics.fuels"},
+		{"kiosk", func(c *Config) {
+			c.Registers.Kiosk = RegisterClassConfig{Count: 2, HandleTimeMin: 1, HandleTimeMax: 3, Preference: 0.4}
+		}, ""},
+		{"negative kiosk preference", func(c *Config) { c.Registers.Kiosk.Preference = -0.1 }, "registers.kiosk"},
+		{"empty mobile time range", func(c *Config) { c.Registers.Mobile.Count = 1 }, "registers.mobile.handle_time"},
+		{"unknown kiosk fuel", func(c *Config) {
+			c.Registers.Kiosk = RegisterClassConfig{Count: 1, HandleTimeMin: 1, HandleTimeMax: 3, Fuels: []string{"hydrogen"}}
+		}, "registers.kiosk.fuels"},
+		{"preferences above 1", func(c *Config) {
+			c.Registers.Kiosk = RegisterClassConfig{Count: 1, HandleTimeMin: 1, HandleTimeMax: 3, Preference: 0.6}
+			c.Registers.Mobile = RegisterClassConfig{Count: 1, HandleTimeMin: 1, HandleTimeMax: 3, Preference: 0.5}
+		}, "preferences of the self-service registers"},
+	}
+	for _, tt := range tests {
+		config := defaultConfig()
+		tt.change(&config)
+		err := config.validate()
+		switch {
+		case tt.err == "" && err != nil:
+			t.Errorf("%s: unexpected error %v", tt.name, err)
+		case tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)):
+			t.Errorf("%s: error = %v, want one about %s", tt.name, err, tt.err)
+		}
+	}
+}
+
+func TestShippedConfigIsValid(t *testing.T) {
+	config, err := loadConfigFile("config.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = config.validate(); err != nil {
+		t.Errorf("config.yaml: %v", err)
+	}
+}
+
+func TestRunCLIExitCodes(t *testing.T) {
+	dir := t.TempDir()
+	invalid := filepath.Join(dir, "invalid.yaml")
+	if err := os.WriteFile(invalid, []byte("registers: {count: 0}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	// Default config without economics and few cars so replications finish quickly
+	small := filepath.Join(dir, "small.yaml")
+	if err := os.WriteFile(small, []byte("cars: {count: 20}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	replicate := func(target string) []string {
+		return []string{"run", "--config", small, "--quiet", "--out", filepath.Join(dir, "summary.yaml"), "--target", target}
+	}
+	tests := []struct {
+		args []string
+		want int
+	}{
+		{[]string{"validate"}, exitOK},
+		{[]string{"validate", "--help"}, exitOK},
+		{[]string{"explode"}, exitConfig},
+		{[]string{"validate", "--config", invalid}, exitConfig},
+		{[]string{"validate", "--config", filepath.Join(dir, "missing.yaml")}, exitConfig},
+		{[]string{"validate", "--set", "cars.count"}, exitConfig},
+		{[]string{"validate", "--unknown-flag"}, exitConfig},
+		{replicate("gas.avg_queue_time=1000"), exitOK},
+		{replicate("economics.profit=1"), exitConfig},
+	}
+	for _, tt := range tests {
+		if got := runCLI(tt.args); got != tt.want {
+			t.Errorf("runCLI(%v) = %d, want %d", tt.args, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+	"goenv/Services"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// Variables
+
+// Dashboard refresh
+
+var dashboardInterval = 100 * time.Millisecond
+var textInterval = 250 * time.Millisecond
+var barWidth = 20
+
+// Routines
+
+// dashboardRoutine shows the station state until stop is closed
+//
+// Terminals get a redrawn ANSI dashboard, other outputs periodic text lines.
+func dashboardRoutine(station *Services.Station, out *os.File, stop <-chan struct{}, done func()) {
+	defer done()
+	draw, interval := drawTextLine, textInterval
+	if isTerminal(out) {
+		draw, interval = drawDashboard, dashboardInterval
+		fmt.Fprint(out, "\033[?25l")
+		defer fmt.Fprint(out, "\033[?25h")
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			// Drawing the final state
+			draw(out, station.Snapshot(), station.TotalCars())
+			return
+		case <-ticker.C:
+			draw(out, station.Snapshot(), station.TotalCars())
+		}
+	}
+}
+
+// Utilities
+
+// drawDashboard redraws the whole screen with one row per stand and register
+func drawDashboard(w io.Writer, snapshot Services.Snapshot, total int) {
+	var b strings.Builder
+	b.WriteString("\033[H\033[J")
+	fmt.Fprintf(&b, "Petrol station  %6d ms   arrived %d/%d   exited %d   building queue %d\n\n",
+		snapshot.Time, snapshot.CarsArrived, total, snapshot.CarsServed, snapshot.BuildingQueue)
+	fmt.Fprintf(&b, "%-22s %-18s %-8s %s\n", "Stand", "Queue", "Serving", "Utilization")
+	for _, stand := range snapshot.Stands {
+		drawRow(&b, fmt.Sprintf("%2d %s", stand.Id, standFuels(stand)), stand)
+	}
+	if len(snapshot.Lanes) > 0 {
+		fmt.Fprintf(&b, "\n%-22s %-18s %s\n", "Lane", "Queue", "Positions")
+		for _, lane := range snapshot.Lanes {
+			queue := fmt.Sprintf("%-14s %2d", strings.Repeat("▪", min(lane.Queue, 14)), lane.Queue)
+			fmt.Fprintf(&b, "%-22s %-18s %d occupied, %d blocked\n", fmt.Sprintf("%2d island %d side %d", lane.Id, lane.Island, lane.Side),
+				queue, lane.Occupied, lane.Blocked)
+		}
+	}
+	fmt.Fprintf(&b, "\n%-22s %-18s %-8s %s\n", "Register", "Queue", "Serving", "Utilization")
+	for _, register := range snapshot.Registers {
+		name := fmt.Sprintf("%2d", register.Id)
+		if register.Class != Services.Staffed {
+			name += " " + string(register.Class)
+		}
+		if register.Closed {
+			name += " closed"
+		}
+		drawRow(&b, name, register)
+	}
+	if len(snapshot.WashBays) > 0 {
+		fmt.Fprintf(&b, "\n%-22s %-18s %-8s %s\n", "Wash bay", "Queue", "Serving", "Utilization")
+		for _, bay := range snapshot.WashBays {
+			bay.Queue = snapshot.WashQueue
+			drawRow(&b, fmt.Sprintf("%2d", bay.Id), bay)
+		}
+	}
+	fmt.Fprintf(&b, "\nAverage wait  stands %.1f ms  registers %.1f ms\n", snapshot.AvgStandWait, snapshot.AvgRegisterWait)
+	io.WriteString(w, b.String())
+}
+
+// drawRow writes a single stand or register row
+func drawRow(b *strings.Builder, name string, state Services.QueueState) {
+	serving := "-"
+	if state.Serving >= 0 {
+		serving = fmt.Sprintf("#%d", state.Serving)
+	}
+	queue := fmt.Sprintf("%-14s %2d", strings.Repeat("▪", min(state.Queue, 14)), state.Queue)
+	utilization := int(state.Utilization * float64(barWidth))
+	fmt.Fprintf(b, "%-22s %-18s %-8s [%s%s] %3.0f%%\n", name, queue, serving,
+		strings.Repeat("█", utilization), strings.Repeat("░", barWidth-utilization), state.Utilization*100)
+}
+
+// drawTextLine writes a single progress line for non-terminal outputs
+func drawTextLine(w io.Writer, snapshot Services.Snapshot, total int) {
+	stands := make([]string, len(snapshot.Stands))
+	for i, stand := range snapshot.Stands {
+		stands[i] = fmt.Sprint(stand.Queue)
+	}
+	registers := make([]string, len(snapshot.Registers))
+	for i, register := range snapshot.Registers {
+		registers[i] = fmt.Sprint(register.Queue)
+	}
+	fmt.Fprintf(w, "t=%dms arrived=%d/%d exited=%d stand_queues=[%s] register_queues=[%s] building_queue=%d avg_stand_wait=%.1f avg_register_wait=%.1f\n",
+		snapshot.Time, snapshot.CarsArrived, total, snapshot.CarsServed,
+		strings.Join(stands, " "), strings.Join(registers, " "), snapshot.BuildingQueue,
+		snapshot.AvgStandWait, snapshot.AvgRegisterWait)
+}
+
+// isTerminal reports whether the file is an interactive terminal
+func isTerminal(file *os.File) bool {
+	info, err := file.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
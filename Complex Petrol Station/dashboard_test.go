@@ -0,0 +1,70 @@
+package main
+
+import (
+	"bytes"
+	"goenv/Services"
+	"strings"
+	"testing"
+)
+
+func TestDrawRow(t *testing.T) {
+	tests := []struct {
+		name    string
+		state   Services.QueueState
+		queue   int
+		serving string
+		filled  int
+		percent string
+	}{
+		{"idle", Services.QueueState{Serving: -1}, 0, "-", 0, "  0%"},
+		{"busy", Services.QueueState{Serving: 12, Queue: 3, Utilization: 0.5}, 3, "#12", 10, " 50%"},
+		{"long queue", Services.QueueState{Serving: 4, Queue: 30, Utilization: 1}, 14, "#4", 20, "100%"},
+	}
+	for _, tt := range tests {
+		var b strings.Builder
+		drawRow(&b, "stand", tt.state)
+		row := b.String()
+		if got := strings.Count(row, "▪"); got != tt.queue {
+			t.Errorf("%s: %d queue marks, want %d in %q", tt.name, got, tt.queue, row)
+		}
+		if got := strings.Count(row, "█"); got != tt.filled || strings.Count(row, "░") != barWidth-tt.filled {
+			t.Errorf("%s: %d filled of %d, want %d in %q", tt.name, got, barWidth, tt.filled, row)
+		}
+		if fields := strings.Fields(row); fields[len(fields)-1] != strings.TrimSpace(tt.percent) || !strings.Contains(row, " "+tt.serving+" ") {
+			t.Errorf("%s: row %q, want serving %s at %s", tt.name, row, tt.serving, tt.percent)
+		}
+	}
+}
+
+func TestDrawTextLine(t *testing.T) {
+	snapshot := Services.Snapshot{
+		Time: 120, CarsArrived: 40, CarsServed: 31, BuildingQueue: 2, AvgStandWait: 3.25, AvgRegisterWait: 1,
+		Stands:    []Services.QueueState{{Queue: 1}, {Queue: 0}},
+		Registers: []Services.QueueState{{Queue: 4}},
+	}
+	var b bytes.Buffer
+	drawTextLine(&b, snapshot, 100)
+	want := "t=120ms arrived=40/100 exited=31 stand_queues=[1 0] register_queues=[4] building_queue=2 avg_stand_wait=3.2 avg_register_wait=1.0\n"
+	if b.String() != want {
+		t.Errorf("line = %q, want %q", b.String(), want)
+	}
+}
+
+func TestDrawDashboardSections(t *testing.T) {
+	snapshot := Services.Snapshot{
+		Stands:    []Services.QueueState{{Id: 0, Fuel: Services.Gas, Fuels: []Services.FuelType{Services.Gas, Services.Diesel}, Serving: -1}},
+		Registers: []Services.QueueState{{Id: 0, Class: Services.Staffed, Serving: -1}, {Id: 1, Class: Services.Kiosk, Serving: -1, Closed: true}},
+	}
+	var b bytes.Buffer
+	drawDashboard(&b, snapshot, 10)
+	for _, want := range []string{" 0 gas+diesel", " 1 kiosk closed", "Register", "Average wait"} {
+		if !strings.Contains(b.String(), want) {
+			t.Errorf("dashboard lacks %q:\n%s", want, b.String())
+		}
+	}
+	for _, absent := range []string{"Lane", "Wash bay"} {
+		if strings.Contains(b.String(), absent) {
+			t.Errorf("dashboard without %s shows it:\n%s", absent, b.String())
+		}
+	}
+}
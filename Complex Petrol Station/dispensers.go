@@ -0,0 +1,205 @@
+package main
+
+import (
+	"fmt"
+	"goenv/Services"
+	"strings"
+	"time"
+)
+
+// Initializations
+
+// ServeTimeConfig is a struct for the fueling time range of one fuel
+type ServeTimeConfig struct {
+	Min int `yaml:"min" json:"min"`
+	Max int `yaml:"max" json:"max"`
+}
+
+// DispenserConfig is a struct for the configuration of multi-product stands
+type DispenserConfig struct {
+	Count      int                        `yaml:"count" json:"count"`
+	Fuels      []string                   `yaml:"fuels" json:"fuels"`
+	ServeTimes map[string]ServeTimeConfig `yaml:"serve_times" json:"serve_times"` // per fuel, stations.<fuel>.serve_time when missing
+}
+
+// StandFuelStats is a struct for output construction of the cars of one fuel at a stand
+type StandFuelStats struct {
+	Fuel      string `yaml:"fuel" json:"fuel"`
+	TotalCars int    `yaml:"total_cars" json:"total_cars"`
+	TotalTime int    `yaml:"total_time" json:"total_time"`
+}
+
+// StandStats is a struct for output construction of a single stand
+type StandStats struct {
+	Id           int              `yaml:"id" json:"id"`
+	Fuels        string           `yaml:"fuels" json:"fuels"` // fuels sold, joined by +
+	TotalCars    int              `yaml:"total_cars" json:"total_cars"`
+	TotalTime    int              `yaml:"total_time" json:"total_time"`
+	AvgQueueTime int              `yaml:"avg_queue_time" json:"avg_queue_time"`
+	ByFuel       []StandFuelStats `yaml:"by_fuel" json:"by_fuel"`
+}
+
+// Utilities
+
+// parseFuels converts fuel names to fuel types, skipping unknown ones
+func parseFuels(names []string) []Services.FuelType {
+	var fuels []Services.FuelType
+	for _, name := range names {
+		if fuel, err := Services.ParseFuelType(name); err == nil {
+			fuels = append(fuels, fuel)
+		}
+	}
+	return fuels
+}
+
+// sells reports whether the dispenser sells the fuel
+func (dispenser DispenserConfig) sells(fuel Services.FuelType) bool {
+	for _, f := range parseFuels(dispenser.Fuels) {
+		if f == fuel {
+			return true
+		}
+	}
+	return false
+}
+
+// fuelPools groups the fuels connected by multi-product stands, every other fuel forms its own group
+func (config *Config) fuelPools() [][]Services.FuelType {
+	pool := make(map[Services.FuelType]int)
+	for i, fuel := range Services.FuelTypes {
+		pool[fuel] = i
+	}
+	// Merging the pools of every dispenser until nothing changes
+	for merged := true; merged; {
+		merged = false
+		for _, dispenser := range config.Dispensers {
+			fuels := parseFuels(dispenser.Fuels)
+			if dispenser.Count == 0 || len(fuels) == 0 {
+				continue
+			}
+			for _, fuel := range fuels[1:] {
+				if pool[fuel] != pool[fuels[0]] {
+					from, to := pool[fuel], pool[fuels[0]]
+					for f, p := range pool {
+						if p == from {
+							pool[f] = to
+						}
+					}
+					merged = true
+				}
+			}
+		}
+	}
+	var pools [][]Services.FuelType
+	index := make(map[int]int)
+	for _, fuel := range Services.FuelTypes {
+		i, ok := index[pool[fuel]]
+		if !ok {
+			i = len(pools)
+			index[pool[fuel]] = i
+			pools = append(pools, nil)
+		}
+		pools[i] = append(pools[i], fuel)
+	}
+	return pools
+}
+
+// poolStands returns the number of stands selling any fuel of the pool, multi-product ones counted once
+func (config *Config) poolStands(pool []Services.FuelType) int {
+	if len(pool) == 1 {
+		return config.standCount(pool[0])
+	}
+	stands := 0
+	for _, fuel := range pool {
+		stands += config.stands()[fuel].Count
+	}
+	for _, dispenser := range config.Dispensers {
+		for _, fuel := range pool {
+			if dispenser.sells(fuel) {
+				stands += dispenser.Count
+				break
+			}
+		}
+	}
+	return stands
+}
+
+// validate checks the fuels and serving times of the dispenser
+func (dispenser DispenserConfig) validate(i int) error {
+	name := fmt.Sprintf("dispensers[%d]", i)
+	if dispenser.Count < 0 {
+		return fmt.Errorf("%s.count must not be negative", name)
+	}
+	if len(dispenser.Fuels) == 0 {
+		return fmt.Errorf("%s.fuels must list at least one fuel", name)
+	}
+	for _, fuel := range dispenser.Fuels {
+		if _, err := Services.ParseFuelType(fuel); err != nil {
+			return fmt.Errorf("%s.fuels: %v", name, err)
+		}
+	}
+	for fuelName, times := range dispenser.ServeTimes {
+		fuel, err := Services.ParseFuelType(fuelName)
+		if err != nil || !dispenser.sells(fuel) {
+			return fmt.Errorf("%s.serve_times has %q which the dispenser does not sell", name, fuelName)
+		}
+		if err := checkRange(name+".serve_times."+fuelName, times.Min, times.Max); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setup converts the config to a station dispenser
+func (dispenser DispenserConfig) setup() Services.DispenserSetup {
+	setup := Services.DispenserSetup{Count: dispenser.Count, Fuels: parseFuels(dispenser.Fuels)}
+	if len(dispenser.ServeTimes) > 0 {
+		setup.MinT = make(map[Services.FuelType]int)
+		setup.MaxT = make(map[Services.FuelType]int)
+		for name, times := range dispenser.ServeTimes {
+			fuel, _ := Services.ParseFuelType(name)
+			setup.MinT[fuel] = times.Min
+			setup.MaxT[fuel] = times.Max
+		}
+	}
+	return setup
+}
+
+// standStats attributes the counted cars and their fueling time to the stands and their fuels
+func standStats(cars []*Services.Car, stands []*Services.FuelStand) []StandStats {
+	var stats []StandStats
+	index := make(map[int]int)
+	for i, stand := range stands {
+		s := StandStats{Id: stand.Id}
+		var names []string
+		for _, fuel := range stand.Fuels {
+			names = append(names, string(fuel))
+			s.ByFuel = append(s.ByFuel, StandFuelStats{Fuel: string(fuel)})
+		}
+		s.Fuels = strings.Join(names, "+")
+		stats = append(stats, s)
+		index[stand.Id] = i
+	}
+	queues := make([]time.Duration, len(stats))
+	for _, car := range cars {
+		i, ok := index[car.Stand]
+		if !ok {
+			continue
+		}
+		s := &stats[i]
+		s.TotalCars++
+		s.TotalTime += int(car.FuelTime)
+		queues[i] += car.StandQueueTime
+		for j := range s.ByFuel {
+			if s.ByFuel[j].Fuel == string(car.Fuel) {
+				s.ByFuel[j].TotalCars++
+				s.ByFuel[j].TotalTime += int(car.FuelTime)
+			}
+		}
+	}
+	for i := range stats {
+		if stats[i].TotalCars != 0 {
+			stats[i].AvgQueueTime = int(queues[i]) / stats[i].TotalCars
+		}
+	}
+	return stats
+}
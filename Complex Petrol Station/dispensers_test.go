@@ -0,0 +1,124 @@
+package main
+
+import (
+	"fmt"
+	"goenv/Services"
+	"strings"
+	"testing"
+)
+
+// dispenserConfig returns the default config with the stands and dispensers
+func dispenserConfig(gas, diesel, lpg, electric int, dispensers ...DispenserConfig) Config {
+	config := defaultConfig()
+	config.Stations.Gas.Count, config.Stations.Diesel.Count = gas, diesel
+	config.Stations.Lpg.Count, config.Stations.Electric.Count = lpg, electric
+	config.Dispensers = dispensers
+	return config
+}
+
+func TestFuelPools(t *testing.T) {
+	tests := []struct {
+		name       string
+		dispensers []DispenserConfig
+		want       string
+	}{
+		{"no dispensers", nil, "[[gas] [diesel] [LPG] [electric]]"},
+		{"gas and diesel", []DispenserConfig{{Count: 1, Fuels: []string{"gas", "diesel"}}}, "[[gas diesel] [LPG] [electric]]"},
+		{"chained", []DispenserConfig{
+			{Count: 1, Fuels: []string{"lpg", "electric"}},
+			{Count: 2, Fuels: []string{"diesel", "lpg"}},
+		}, "[[gas] [diesel LPG electric]]"},
+		{"without count", []DispenserConfig{{Count: 0, Fuels: []string{"gas", "diesel"}}}, "[[gas] [diesel] [LPG] [electric]]"},
+		{"single fuel dispenser", []DispenserConfig{{Count: 1, Fuels: []string{"electric"}}}, "[[gas] [diesel] [LPG] [electric]]"},
+	}
+	for _, tt := range tests {
+		config := dispenserConfig(1, 1, 1, 1, tt.dispensers...)
+		if got := fmt.Sprint(config.fuelPools()); got != tt.want {
+			t.Errorf("%s: fuelPools = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestPoolStands(t *testing.T) {
+	config := dispenserConfig(3, 2, 1, 0,
+		DispenserConfig{Count: 2, Fuels: []string{"gas", "diesel"}},
+		DispenserConfig{Count: 1, Fuels: []string{"electric"}},
+	)
+	tests := []struct {
+		pool []Services.FuelType
+		want int
+	}{
+		{[]Services.FuelType{Services.Gas, Services.Diesel}, 3 + 2 + 2},
+		{[]Services.FuelType{Services.LPG}, 1},
+		{[]Services.FuelType{Services.Electric}, 1},
+		{[]Services.FuelType{Services.Gas}, 3 + 2},
+	}
+	for _, tt := range tests {
+		if got := config.poolStands(tt.pool); got != tt.want {
+			t.Errorf("poolStands(%v) = %d, want %d", tt.pool, got, tt.want)
+		}
+	}
+	if got := config.totalStands(); got != 3+2+1+2+1 {
+		t.Errorf("totalStands = %d, want dispensers counted once", got)
+	}
+}
+
+func TestDispenserValidate(t *testing.T) {
+	tests := []struct {
+		name      string
+		dispenser DispenserConfig
+		invalid   string
+	}{
+		{"gas and diesel", DispenserConfig{Count: 2, Fuels: []string{"gas", "diesel"}}, ""},
+		{"own serve times", DispenserConfig{Count: 1, Fuels: []string{"gas", "diesel"},
+			ServeTimes: map[string]ServeTimeConfig{"diesel": {Min: 2, Max: 6}}}, ""},
+		{"negative count", DispenserConfig{Count: -1, Fuels: []string{"gas"}}, "dispensers[3].count"},
+		{"no fuels", DispenserConfig{Count: 1}, "dispensers[3].fuels must list"},
+		{"unknown fuel", DispenserConfig{Count: 1, Fuels: []string{"gas", "kerosene"}}, "dispensers[3].fuels"},
+		{"serve time of another fuel", DispenserConfig{Count: 1, Fuels: []string{"gas"},
+			ServeTimes: map[string]ServeTimeConfig{"lpg": {Min: 2, Max: 6}}}, "does not sell"},
+		{"empty serve time range", DispenserConfig{Count: 1, Fuels: []string{"gas"},
+			ServeTimes: map[string]ServeTimeConfig{"gas": {Min: 6, Max: 6}}}, "dispensers[3].serve_times.gas"},
+	}
+	for _, tt := range tests {
+		err := tt.dispenser.validate(3)
+		if (err != nil) != (tt.invalid != "") || err != nil && !strings.Contains(err.Error(), tt.invalid) {
+			t.Errorf("%s: validate = %v, want error about %q", tt.name, err, tt.invalid)
+		}
+	}
+}
+
+func TestDispenserSetup(t *testing.T) {
+	setup := DispenserConfig{Count: 2, Fuels: []string{"diesel", "gas"},
+		ServeTimes: map[string]ServeTimeConfig{"gas": {Min: 1, Max: 3}}}.setup()
+	if setup.Count != 2 || fmt.Sprint(setup.Fuels) != "[diesel gas]" || setup.MinT[Services.Gas] != 1 || setup.MaxT[Services.Gas] != 3 {
+		t.Errorf("setup = %+v", setup)
+	}
+	if setup := (DispenserConfig{Count: 1, Fuels: []string{"gas"}}).setup(); setup.MinT != nil {
+		t.Errorf("setup without serve times has times %v", setup.MinT)
+	}
+}
+
+func TestStandStats(t *testing.T) {
+	stands := []*Services.FuelStand{
+		Services.NewFuelStand(1, Services.LPG, 1),
+		Services.NewDispenser(2, Services.DispenserSetup{Fuels: []Services.FuelType{Services.Gas, Services.Diesel}}, 1),
+	}
+	cars := []*Services.Car{
+		{Stand: 1, Fuel: Services.LPG, FuelTime: 8, StandQueueTime: 4},
+		{Stand: 2, Fuel: Services.Gas, FuelTime: 3, StandQueueTime: 1},
+		{Stand: 2, Fuel: Services.Diesel, FuelTime: 5, StandQueueTime: 5},
+		{Stand: 2, Fuel: Services.Gas, FuelTime: 2, StandQueueTime: 0},
+		{Stand: 9, Fuel: Services.Gas, FuelTime: 50},
+	}
+	stats := standStats(cars, stands)
+	want := []string{
+		"1 LPG 1 8 4 [{LPG 1 8}]",
+		"2 gas+diesel 3 10 2 [{gas 2 5} {diesel 1 5}]",
+	}
+	for i, s := range stats {
+		if got := fmt.Sprint(s.Id, " ", s.Fuels, " ", s.TotalCars, " ", s.TotalTime, " ", s.AvgQueueTime, " ", s.ByFuel); got != want[i] {
+			t.Errorf("stand %d = %s, want %s", s.Id, got, want[i])
+		}
+	}
+}
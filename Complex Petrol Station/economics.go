@@ -0,0 +1,201 @@
+package main
+
+import (
+	"fmt"
+	"goenv/Services"
+	"math"
+	"sort"
+	"strings"
+)
+
+// Initializations
+
+// FuelPricing is a struct for the price and volume configuration of a single fuel
+type FuelPricing struct {
+	Price     float64 `yaml:"price" json:"price"`           // price per unit of fuel
+	Margin    float64 `yaml:"margin" json:"margin"`         // margin per unit of fuel
+	VolumeMin float64 `yaml:"volume_min" json:"volume_min"` // units bought by a car
+	VolumeMax float64 `yaml:"volume_max" json:"volume_max"`
+}
+
+// EconomicsConfig is a struct for the configuration of prices and operating costs
+type EconomicsConfig struct {
+	Hour  int `yaml:"hour" json:"hour"` // simulated ms per hour of operation
+	Fuels struct {
+		Gas      FuelPricing `yaml:"gas" json:"gas"`
+		Diesel   FuelPricing `yaml:"diesel" json:"diesel"`
+		Lpg      FuelPricing `yaml:"lpg" json:"lpg"`
+		Electric FuelPricing `yaml:"electric" json:"electric"`
+	} `yaml:"fuels" json:"fuels"`
+	Shop struct {
+		BasketMin float64 `yaml:"basket_min" json:"basket_min"`
+		BasketMax float64 `yaml:"basket_max" json:"basket_max"`
+		Margin    float64 `yaml:"margin" json:"margin"` // share of the basket kept as margin
+	} `yaml:"shop" json:"shop"`
+	Costs struct {
+		StandHour    float64 `yaml:"stand_hour" json:"stand_hour"`       // per stand and hour
+		RegisterHour float64 `yaml:"register_hour" json:"register_hour"` // staff cost per register and hour
+		DemandCharge float64 `yaml:"demand_charge" json:"demand_charge"` // per kW of peak electric demand
+		ChargerPower float64 `yaml:"charger_power" json:"charger_power"` // kW drawn by a charging car
+	} `yaml:"costs" json:"costs"`
+}
+
+// FuelEconomics is a struct for output construction of the money made on one fuel
+type FuelEconomics struct {
+	Cars        int     `yaml:"cars" json:"cars"`
+	Volume      float64 `yaml:"volume" json:"volume"`
+	AvgPrice    float64 `yaml:"avg_price" json:"avg_price"` // volume weighted
+	Revenue     float64 `yaml:"revenue" json:"revenue"`
+	GrossMargin float64 `yaml:"gross_margin" json:"gross_margin"`
+	Balked      int     `yaml:"balked" json:"balked"`
+	LostRevenue float64 `yaml:"lost_revenue" json:"lost_revenue"`
+}
+
+// Economics is a struct for output construction of the money made during a run
+type Economics struct {
+	FuelRevenue float64       `yaml:"fuel_revenue" json:"fuel_revenue"`
+	ShopRevenue float64       `yaml:"shop_revenue" json:"shop_revenue"`
+	Revenue     float64       `yaml:"revenue" json:"revenue"`
+	GrossMargin float64       `yaml:"gross_margin" json:"gross_margin"`
+	Cost        float64       `yaml:"cost" json:"cost"`
+	Profit      float64       `yaml:"profit" json:"profit"`
+	LostRevenue float64       `yaml:"lost_revenue" json:"lost_revenue"`
+	Hours       float64       `yaml:"hours" json:"hours"`
+	PeakDemand  float64       `yaml:"peak_demand" json:"peak_demand"` // kW
+	Gas         FuelEconomics `yaml:"Gas" json:"Gas"`
+	Diesel      FuelEconomics `yaml:"Diesel" json:"Diesel"`
+	LPG         FuelEconomics `yaml:"LPG" json:"LPG"`
+	Electric    FuelEconomics `yaml:"Electric" json:"Electric"`
+}
+
+// Utilities
+
+// pricing returns the pricing of every fuel type
+func (economics *EconomicsConfig) pricing() map[Services.FuelType]*FuelPricing {
+	return map[Services.FuelType]*FuelPricing{
+		Services.Gas:      &economics.Fuels.Gas,
+		Services.Diesel:   &economics.Fuels.Diesel,
+		Services.LPG:      &economics.Fuels.Lpg,
+		Services.Electric: &economics.Fuels.Electric,
+	}
+}
+
+// byFuel returns the money made on every fuel type
+func (economics *Economics) byFuel() map[Services.FuelType]*FuelEconomics {
+	return map[Services.FuelType]*FuelEconomics{
+		Services.Gas:      &economics.Gas,
+		Services.Diesel:   &economics.Diesel,
+		Services.LPG:      &economics.LPG,
+		Services.Electric: &economics.Electric,
+	}
+}
+
+// enabled reports whether any price or cost is configured
+func (economics EconomicsConfig) enabled() bool {
+	for _, pricing := range economics.pricing() {
+		if pricing.Price > 0 || pricing.Margin > 0 {
+			return true
+		}
+	}
+	costs := economics.Costs
+	return economics.Shop.BasketMax > 0 || costs.StandHour > 0 || costs.RegisterHour > 0 || costs.DemandCharge > 0
+}
+
+// validate checks that prices, volumes and costs are usable
+func (economics EconomicsConfig) validate() error {
+	if economics.Hour < 1 {
+		return fmt.Errorf("economics.hour must be at least 1")
+	}
+	for fuel, pricing := range economics.pricing() {
+		if pricing.Price < 0 || pricing.VolumeMin < 0 || pricing.VolumeMax < pricing.VolumeMin {
+			return fmt.Errorf("economics.fuels.%s needs a non-negative price and volume_min not above volume_max",
+				strings.ToLower(string(fuel)))
+		}
+	}
+	shop, costs := economics.Shop, economics.Costs
+	if shop.BasketMin < 0 || shop.BasketMax < shop.BasketMin {
+		return fmt.Errorf("economics.shop.basket_min must be non-negative and not above basket_max")
+	}
+	if costs.StandHour < 0 || costs.RegisterHour < 0 || costs.DemandCharge < 0 || costs.ChargerPower < 0 {
+		return fmt.Errorf("economics.costs must not be negative")
+	}
+	return nil
+}
+
+// evaluate computes revenue, margin, cost and profit of all cars of a run
+//
+// Money is counted for the whole run regardless of the warm-up and staff are paid
+// for the hours their registers were staffed. Cars pay the
+// price quoted on arrival and the margin moves with it. Balked cars are valued
+// at the expected fuel and shop revenue of a car of their fuel at its base price.
+func (economics EconomicsConfig) evaluate(cars []*Services.Car, config Config, balked map[Services.FuelType]int, staffHours float64) *Economics {
+	result := &Economics{}
+	pricing := economics.pricing()
+	fuels := result.byFuel()
+	expectedBasket := (economics.Shop.BasketMin + economics.Shop.BasketMax) / 2
+	end := 0.0
+	for _, car := range cars {
+		fuel := pricing[car.Fuel]
+		price := fuel.Price
+		if car.Price > 0 {
+			price = car.Price
+		}
+		stats := fuels[car.Fuel]
+		stats.Cars++
+		stats.Volume += car.Volume
+		stats.AvgPrice += car.Volume * price
+		stats.Revenue += car.Volume*price + car.ShopBasket
+		stats.GrossMargin += car.Volume*(fuel.Margin+price-fuel.Price) + car.ShopBasket*economics.Shop.Margin
+		result.FuelRevenue += car.Volume * price
+		result.ShopRevenue += car.ShopBasket
+		end = math.Max(end, float64(car.ArrivalTime+car.TotalTime))
+	}
+	for _, fuel := range Services.FuelTypes {
+		stats := fuels[fuel]
+		if stats.Volume > 0 {
+			stats.AvgPrice /= stats.Volume
+		}
+		stats.Balked = balked[fuel]
+		expected := pricing[fuel].Price*(pricing[fuel].VolumeMin+pricing[fuel].VolumeMax)/2 + expectedBasket
+		stats.LostRevenue = float64(stats.Balked) * expected
+		result.Revenue += stats.Revenue
+		result.GrossMargin += stats.GrossMargin
+		result.LostRevenue += stats.LostRevenue
+	}
+
+	// Operating costs
+	stands := config.totalStands()
+	result.Hours = end / float64(economics.Hour)
+	result.PeakDemand = float64(peakCharging(cars)) * economics.Costs.ChargerPower
+	result.Cost = economics.Costs.StandHour*float64(stands)*result.Hours +
+		economics.Costs.RegisterHour*staffHours +
+		economics.Costs.DemandCharge*result.PeakDemand
+	result.Profit = result.GrossMargin - result.Cost
+	return result
+}
+
+// peakCharging returns the largest number of electric cars charging at the same time
+func peakCharging(cars []*Services.Car) int {
+	type change struct {
+		time  float64
+		delta int
+	}
+	var changes []change
+	for _, car := range cars {
+		if car.Fuel != Services.Electric {
+			continue
+		}
+		start := float64(car.ArrivalTime + car.StandQueueTime)
+		changes = append(changes, change{start, 1}, change{start + float64(car.FuelTime), -1})
+	}
+	// Ends before starts at the same moment
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].time < changes[j].time || (changes[i].time == changes[j].time && changes[i].delta < changes[j].delta)
+	})
+	peak, charging := 0, 0
+	for _, c := range changes {
+		charging += c.delta
+		peak = max(peak, charging)
+	}
+	return peak
+}
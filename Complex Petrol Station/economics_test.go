@@ -0,0 +1,127 @@
+package main
+
+import (
+	"goenv/Services"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEvaluateLostRevenue(t *testing.T) {
+	config := defaultConfig()
+	config.Economics.Fuels.Gas = FuelPricing{Price: 2, Margin: 0.5, VolumeMin: 10, VolumeMax: 30}
+	config.Economics.Shop.BasketMin, config.Economics.Shop.BasketMax = 0, 10
+	cars := []*Services.Car{
+		{Fuel: Services.Gas, Volume: 20, Price: 2.2, ShopBasket: 4},
+		{Fuel: Services.Gas, Volume: 10},
+	}
+	balked := map[Services.FuelType]int{Services.Gas: 3, Services.Diesel: 1}
+	result := config.Economics.evaluate(cars, config, balked, 0)
+	tests := []struct {
+		name      string
+		got, want float64
+	}{
+		// Quoted price for the first car, base price for the second
+		{"gas revenue", result.Gas.Revenue, 20*2.2 + 4 + 10*2},
+		{"gas margin", result.Gas.GrossMargin, 20*(0.5+0.2) + 4*config.Economics.Shop.Margin + 10*0.5},
+		// Expected spend of a gas car is 20 units at 2 plus a basket of 5
+		{"gas lost revenue", result.Gas.LostRevenue, 3 * 45},
+		{"diesel lost revenue", result.Diesel.LostRevenue, 5},
+		{"total lost revenue", result.LostRevenue, 3*45 + 5},
+	}
+	for _, tt := range tests {
+		if math.Abs(tt.got-tt.want) > 1e-9 {
+			t.Errorf("%s = %v, want %v", tt.name, tt.got, tt.want)
+		}
+	}
+	if result.Gas.Balked != 3 || result.LPG.Balked != 0 {
+		t.Errorf("balked gas %d and lpg %d, want 3 and 0", result.Gas.Balked, result.LPG.Balked)
+	}
+}
+
+func TestEconomicsEnabledAndValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		change  func(*EconomicsConfig)
+		enabled bool
+		invalid string
+	}{
+		{"shipped", func(e *EconomicsConfig) {}, false, ""},
+		{"fuel price", func(e *EconomicsConfig) { e.Fuels.Diesel.Price = 1.7 }, true, ""},
+		{"fuel margin", func(e *EconomicsConfig) { e.Fuels.Lpg.Margin = 0.1 }, true, ""},
+		{"shop basket", func(e *EconomicsConfig) { e.Shop.BasketMax = 15 }, true, ""},
+		{"demand charge", func(e *EconomicsConfig) { e.Costs.DemandCharge = 2 }, true, ""},
+		{"charger power alone", func(e *EconomicsConfig) { e.Costs.ChargerPower = 150 }, false, ""},
+		{"no hour", func(e *EconomicsConfig) { e.Hour = 0 }, false, "economics.hour"},
+		{"negative price", func(e *EconomicsConfig) { e.Fuels.Gas.Price = -1 }, false, "economics.fuels.gas"},
+		{"volume range", func(e *EconomicsConfig) { e.Fuels.Electric.VolumeMin = 5 }, false, "economics.fuels.electric"},
+		{"basket range", func(e *EconomicsConfig) { e.Shop.BasketMin = 5 }, false, "economics.shop"},
+		{"negative cost", func(e *EconomicsConfig) { e.Costs.StandHour = -3 }, false, "economics.costs"},
+	}
+	for _, tt := range tests {
+		economics := defaultConfig().Economics
+		tt.change(&economics)
+		if got := economics.enabled(); got != tt.enabled {
+			t.Errorf("%s: enabled = %v, want %v", tt.name, got, tt.enabled)
+		}
+		err := economics.validate()
+		if (err != nil) != (tt.invalid != "") || err != nil && !strings.Contains(err.Error(), tt.invalid) {
+			t.Errorf("%s: validate = %v, want error about %q", tt.name, err, tt.invalid)
+		}
+	}
+}
+
+func TestPeakCharging(t *testing.T) {
+	charging := func(arrival, queue, fuel int) *Services.Car {
+		return &Services.Car{Fuel: Services.Electric, ArrivalTime: time.Duration(arrival),
+			StandQueueTime: time.Duration(queue), FuelTime: time.Duration(fuel)}
+	}
+	tests := []struct {
+		name string
+		cars []*Services.Car
+		want int
+	}{
+		{"none", nil, 0},
+		{"other fuels", []*Services.Car{{Fuel: Services.Gas, FuelTime: 10}, {Fuel: Services.Gas, FuelTime: 10}}, 0},
+		{"overlapping", []*Services.Car{charging(0, 0, 10), charging(5, 0, 10), charging(8, 0, 10)}, 3},
+		{"queue delays start", []*Services.Car{charging(0, 0, 10), charging(2, 8, 10)}, 1},
+		{"back to back", []*Services.Car{charging(0, 0, 10), charging(10, 0, 10), charging(20, 0, 10)}, 1},
+	}
+	for _, tt := range tests {
+		if got := peakCharging(tt.cars); got != tt.want {
+			t.Errorf("%s: peakCharging = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestEvaluateCosts(t *testing.T) {
+	config := defaultConfig()
+	config.Stations.Gas.Count, config.Stations.Diesel.Count, config.Stations.Lpg.Count, config.Stations.Electric.Count = 2, 1, 0, 1
+	config.Economics.Hour = 100
+	config.Economics.Fuels.Electric = FuelPricing{Price: 0.5, Margin: 0.2}
+	config.Economics.Costs.StandHour = 3
+	config.Economics.Costs.RegisterHour = 20
+	config.Economics.Costs.DemandCharge = 0.1
+	config.Economics.Costs.ChargerPower = 50
+	cars := []*Services.Car{
+		{Fuel: Services.Electric, Volume: 40, FuelTime: 100},
+		{Fuel: Services.Electric, Volume: 20, ArrivalTime: 50, FuelTime: 100, TotalTime: 150},
+	}
+	result := config.Economics.evaluate(cars, config, nil, 1.5)
+	tests := []struct {
+		name      string
+		got, want float64
+	}{
+		{"hours until the last car left", result.Hours, 2},
+		{"peak demand of two chargers", result.PeakDemand, 100},
+		{"stand, staff and demand cost", result.Cost, 3*4*2 + 20*1.5 + 0.1*100},
+		{"profit", result.Profit, 60*0.2 - (24 + 30 + 10)},
+		{"average price", result.Electric.AvgPrice, 0.5},
+	}
+	for _, tt := range tests {
+		if math.Abs(tt.got-tt.want) > 1e-9 {
+			t.Errorf("%s = %v, want %v", tt.name, tt.got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"fmt"
+	"goenv/Services"
+	"strings"
+	"time"
+)
+
+// Initializations
+
+// IslandConfig is a struct for the configuration of a pump island
+type IslandConfig struct {
+	Fuel      string `yaml:"fuel" json:"fuel"`
+	Sides     int    `yaml:"sides" json:"sides"`         // lanes along the island, 2 for dual-sided pumps
+	Positions int    `yaml:"positions" json:"positions"` // pumps per lane, one behind the other
+}
+
+// LayoutConfig is a struct for the configuration of the forecourt, stands are independent without islands
+type LayoutConfig struct {
+	Islands []IslandConfig `yaml:"islands" json:"islands"`
+}
+
+// LaneStats is a struct for output construction of a single lane
+type LaneStats struct {
+	Id             int    `yaml:"id" json:"id"`
+	Island         int    `yaml:"island" json:"island"`
+	Side           int    `yaml:"side" json:"side"`
+	Fuel           string `yaml:"fuel" json:"fuel"`
+	TotalCars      int    `yaml:"total_cars" json:"total_cars"`
+	BlockedCars    int    `yaml:"blocked_cars" json:"blocked_cars"`
+	TotalBlocked   int    `yaml:"total_blocked_time" json:"total_blocked_time"`
+	MaxBlockedTime int    `yaml:"max_blocked_time" json:"max_blocked_time"`
+}
+
+// LayoutStats is a struct for output construction of the blocking in the lanes
+type LayoutStats struct {
+	BlockedCars    int         `yaml:"blocked_cars" json:"blocked_cars"`
+	AvgBlockedTime int         `yaml:"avg_blocked_time" json:"avg_blocked_time"` // over all counted cars
+	MaxBlockedTime int         `yaml:"max_blocked_time" json:"max_blocked_time"`
+	Lanes          []LaneStats `yaml:"lanes" json:"lanes"`
+}
+
+// Utilities
+
+// enabled reports whether the forecourt is built from islands
+func (layout LayoutConfig) enabled() bool {
+	return len(layout.Islands) > 0
+}
+
+// positions returns the number of pumps selling the fuel over all islands
+func (layout LayoutConfig) positions(fuel Services.FuelType) int {
+	positions := 0
+	for _, island := range layout.Islands {
+		if islandFuel, _ := Services.ParseFuelType(island.Fuel); islandFuel == fuel {
+			positions += island.Sides * island.Positions
+		}
+	}
+	return positions
+}
+
+// checkCountKey rejects varying the stand count of a fuel, which the islands replace
+func (layout LayoutConfig) checkCountKey(key string) error {
+	key = strings.ToLower(key)
+	if layout.enabled() && strings.HasPrefix(key, "stations.") && strings.HasSuffix(key, ".count") {
+		return fmt.Errorf("%s is ignored with layout.islands, which set the stands", key)
+	}
+	return nil
+}
+
+// validate checks the islands
+func (layout LayoutConfig) validate() error {
+	for i, island := range layout.Islands {
+		if _, err := Services.ParseFuelType(island.Fuel); err != nil {
+			return fmt.Errorf("layout.islands[%d].fuel: %v", i, err)
+		}
+		if island.Sides < 1 || island.Positions < 1 {
+			return fmt.Errorf("layout.islands[%d] needs at least 1 side and 1 position", i)
+		}
+	}
+	return nil
+}
+
+// islands converts the config to station islands
+func (layout LayoutConfig) islands() []Services.IslandSetup {
+	var islands []Services.IslandSetup
+	for _, island := range layout.Islands {
+		fuel, _ := Services.ParseFuelType(island.Fuel)
+		islands = append(islands, Services.IslandSetup{Fuel: fuel, Sides: island.Sides, Positions: island.Positions})
+	}
+	return islands
+}
+
+// layoutStats summarizes how long fueled cars were blocked in every lane
+func layoutStats(cars []*Services.Car, lanes []*Services.Lane) *LayoutStats {
+	stats := &LayoutStats{}
+	laneOf := make(map[int]int)
+	for i, lane := range lanes {
+		stats.Lanes = append(stats.Lanes, LaneStats{Id: lane.Id, Island: lane.Island, Side: lane.Side, Fuel: string(lane.Type)})
+		for _, position := range lane.Positions {
+			laneOf[position.Id] = i
+		}
+	}
+	var total time.Duration
+	counted := 0
+	for _, car := range cars {
+		// Cars that did not fuel at a lane position are left out
+		i, ok := laneOf[car.Stand]
+		if !ok {
+			continue
+		}
+		counted++
+		lane := &stats.Lanes[i]
+		lane.TotalCars++
+		if car.BlockedTime > 0 {
+			lane.BlockedCars++
+			stats.BlockedCars++
+		}
+		lane.TotalBlocked += int(car.BlockedTime)
+		lane.MaxBlockedTime = max(lane.MaxBlockedTime, int(car.BlockedTime))
+		stats.MaxBlockedTime = max(stats.MaxBlockedTime, int(car.BlockedTime))
+		total += car.BlockedTime
+	}
+	if counted != 0 {
+		stats.AvgBlockedTime = int(total) / counted
+	}
+	return stats
+}
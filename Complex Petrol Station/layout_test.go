@@ -0,0 +1,83 @@
+package main
+
+import (
+	"goenv/Services"
+	"strings"
+	"testing"
+)
+
+func TestLayoutValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		islands []IslandConfig
+		invalid string
+	}{
+		{"independent stands", nil, ""},
+		{"islands", []IslandConfig{{Fuel: "gas", Sides: 2, Positions: 2}, {Fuel: "ev", Sides: 1, Positions: 1}}, ""},
+		{"unknown fuel", []IslandConfig{{Fuel: "gas", Sides: 1, Positions: 1}, {Fuel: "coal", Sides: 1, Positions: 1}}, "layout.islands[1].fuel"},
+		{"no sides", []IslandConfig{{Fuel: "gas", Sides: 0, Positions: 2}}, "layout.islands[0] needs"},
+		{"no positions", []IslandConfig{{Fuel: "gas", Sides: 2, Positions: 0}}, "layout.islands[0] needs"},
+	}
+	for _, tt := range tests {
+		err := LayoutConfig{Islands: tt.islands}.validate()
+		if (err != nil) != (tt.invalid != "") || err != nil && !strings.Contains(err.Error(), tt.invalid) {
+			t.Errorf("%s: validate = %v, want error about %q", tt.name, err, tt.invalid)
+		}
+	}
+}
+
+func TestLayoutPositions(t *testing.T) {
+	layout := LayoutConfig{Islands: []IslandConfig{
+		{Fuel: "gas", Sides: 2, Positions: 2},
+		{Fuel: "Petrol", Sides: 1, Positions: 3},
+		{Fuel: "diesel", Sides: 2, Positions: 1},
+	}}
+	tests := []struct {
+		fuel Services.FuelType
+		want int
+	}{
+		{Services.Gas, 7},
+		{Services.Diesel, 2},
+		{Services.LPG, 0},
+	}
+	for _, tt := range tests {
+		if got := layout.positions(tt.fuel); got != tt.want {
+			t.Errorf("positions(%s) = %d, want %d", tt.fuel, got, tt.want)
+		}
+	}
+	islands := layout.islands()
+	if len(islands) != 3 || islands[1] != (Services.IslandSetup{Fuel: Services.Gas, Sides: 1, Positions: 3}) {
+		t.Errorf("islands = %+v", islands)
+	}
+}
+
+func TestLayoutStats(t *testing.T) {
+	lanes := []*Services.Lane{
+		Services.NewLane(0, 0, 0, Services.Gas, 2, 0, 1),
+		Services.NewLane(1, 0, 1, Services.Gas, 2, 2, 1),
+	}
+	cars := []*Services.Car{
+		{Stand: 0, BlockedTime: 0},
+		{Stand: 1, BlockedTime: 8},
+		{Stand: 3, BlockedTime: 4},
+		{Stand: 2, BlockedTime: 0},
+		// Not at any lane position
+		{Stand: 7, BlockedTime: 20},
+	}
+	stats := layoutStats(cars, lanes)
+	if stats.BlockedCars != 2 || stats.AvgBlockedTime != 3 || stats.MaxBlockedTime != 8 {
+		t.Errorf("layout stats = %+v, want 2 blocked cars averaging 3 and at most 8", stats)
+	}
+	want := []LaneStats{
+		{Id: 0, Island: 0, Side: 0, Fuel: "gas", TotalCars: 2, BlockedCars: 1, TotalBlocked: 8, MaxBlockedTime: 8},
+		{Id: 1, Island: 0, Side: 1, Fuel: "gas", TotalCars: 2, BlockedCars: 1, TotalBlocked: 4, MaxBlockedTime: 4},
+	}
+	for i := range want {
+		if stats.Lanes[i] != want[i] {
+			t.Errorf("lane %d = %+v, want %+v", i, stats.Lanes[i], want[i])
+		}
+	}
+	if stats := layoutStats(cars, nil); len(stats.Lanes) != 0 || stats.BlockedCars != 0 {
+		t.Errorf("layout stats without lanes = %+v, want none", stats)
+	}
+}
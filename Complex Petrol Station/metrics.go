@@ -0,0 +1,296 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"goenv/Services"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Variables
+
+// Histogram buckets in simulated ms
+
+var metricBuckets = []float64{1, 2, 5, 10, 20, 50, 100, 200, 500, 1000}
+
+// Initializations
+
+// histogram counts observations into the metric buckets
+type histogram struct {
+	counts []uint64 // cumulative count per bucket
+	count  uint64
+	sum    float64
+}
+
+// observe records a single value
+func (h *histogram) observe(value float64) {
+	if h.counts == nil {
+		h.counts = make([]uint64, len(metricBuckets))
+	}
+	for i, bound := range metricBuckets {
+		if value <= bound {
+			h.counts[i]++
+		}
+	}
+	h.count++
+	h.sum += value
+}
+
+// runMetrics holds the metrics of a single station run
+type runMetrics struct {
+	mu           sync.Mutex
+	labels       []string // constant label names and values of the run
+	station      *Services.Station
+	queueTimes   map[[2]string]*histogram // by stage and fuel
+	serviceTimes map[[2]string]*histogram // by stage and fuel
+}
+
+// newRunMetrics creates the metrics of an open station, labels are name value pairs
+func newRunMetrics(station *Services.Station, labels ...string) *runMetrics {
+	return &runMetrics{
+		labels:       labels,
+		station:      station,
+		queueTimes:   make(map[[2]string]*histogram),
+		serviceTimes: make(map[[2]string]*histogram),
+	}
+}
+
+// metricsRegistry exposes the metrics of registered runs in the prometheus text format
+type metricsRegistry struct {
+	mu   sync.Mutex
+	runs map[string]*runMetrics
+}
+
+// newMetricsRegistry creates an empty registry
+func newMetricsRegistry() *metricsRegistry {
+	return &metricsRegistry{runs: make(map[string]*runMetrics)}
+}
+
+// add registers the metrics of a run under a key
+func (mr *metricsRegistry) add(key string, rm *runMetrics) {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+	mr.runs[key] = rm
+}
+
+// remove drops the metrics of a run
+func (mr *metricsRegistry) remove(key string) {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+	delete(mr.runs, key)
+}
+
+// Routines
+
+// collectCars observes the times of cars passing from exit to the returned queue
+func (rm *runMetrics) collectCars(exit <-chan *Services.Car) <-chan *Services.Car {
+	forwarded := make(chan *Services.Car)
+	go func() {
+		defer close(forwarded)
+		for car := range exit {
+			fuel := string(car.Fuel)
+			rm.mu.Lock()
+			observe(rm.queueTimes, "stand", fuel, float64(car.StandQueueTime))
+			observe(rm.queueTimes, "register", fuel, float64(car.RegisterQueueTime))
+			observe(rm.serviceTimes, "fuel", fuel, float64(car.FuelTime))
+			observe(rm.serviceTimes, "payment", fuel, float64(car.PayTime))
+			if car.Wash {
+				observe(rm.queueTimes, "wash", fuel, float64(car.WashQueueTime))
+				observe(rm.serviceTimes, "wash", fuel, float64(car.WashTime))
+			}
+			rm.mu.Unlock()
+			forwarded <- car
+		}
+	}()
+	return forwarded
+}
+
+// Handlers
+
+// ServeHTTP writes the metrics of all registered runs
+func (mr *metricsRegistry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	buffered := bufio.NewWriter(w)
+	mr.write(buffered)
+	buffered.Flush()
+}
+
+// Utilities
+
+// write writes every metric family once with the samples of all runs
+func (mr *metricsRegistry) write(w io.Writer) {
+	mr.mu.Lock()
+	keys := make([]string, 0, len(mr.runs))
+	for key := range mr.runs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	runs := make([]*runMetrics, len(keys))
+	for i, key := range keys {
+		runs[i] = mr.runs[key]
+	}
+	mr.mu.Unlock()
+	snapshots := make([]Services.Snapshot, len(runs))
+	for i, rm := range runs {
+		snapshots[i] = rm.station.Snapshot()
+	}
+
+	// Counters
+	counters := []struct {
+		name, help string
+		value      func(Services.FuelState) int
+	}{
+		{"petrol_cars_arrived_total", "Cars arrived at the station.", func(f Services.FuelState) int { return f.Arrived }},
+		{"petrol_cars_served_total", "Cars that paid and left the station.", func(f Services.FuelState) int { return f.Served }},
+		{"petrol_cars_balked_total", "Cars that left without joining a queue.", func(f Services.FuelState) int { return f.Balked }},
+	}
+	for _, counter := range counters {
+		writeHeader(w, counter.name, counter.help, "counter")
+		for i, rm := range runs {
+			for _, fuel := range snapshots[i].Fuels {
+				writeSample(w, counter.name, rm.labelSet("fuel", string(fuel.Fuel)), float64(counter.value(fuel)))
+			}
+		}
+	}
+
+	// Gauges
+	writeHeader(w, "petrol_fuel_price", "Current price per unit of a fuel, zero without a pricing strategy.", "gauge")
+	for i, rm := range runs {
+		for _, fuel := range snapshots[i].Fuels {
+			writeSample(w, "petrol_fuel_price", rm.labelSet("fuel", string(fuel.Fuel)), fuel.Price)
+		}
+	}
+	writeHeader(w, "petrol_stand_queue_length", "Cars waiting in the queue of a stand.", "gauge")
+	for i, rm := range runs {
+		for _, stand := range snapshots[i].Stands {
+			writeSample(w, "petrol_stand_queue_length", rm.labelSet("stand", strconv.Itoa(stand.Id), "fuel", standFuels(stand)), float64(stand.Queue))
+		}
+	}
+	writeHeader(w, "petrol_lane_queue_length", "Cars waiting to enter a lane of a pump island.", "gauge")
+	for i, rm := range runs {
+		for _, lane := range snapshots[i].Lanes {
+			writeSample(w, "petrol_lane_queue_length", rm.labelSet("lane", strconv.Itoa(lane.Id), "fuel", string(lane.Fuel)), float64(lane.Queue))
+		}
+	}
+	writeHeader(w, "petrol_lane_blocked_cars", "Fueled cars waiting for the cars ahead in their lane to leave.", "gauge")
+	for i, rm := range runs {
+		for _, lane := range snapshots[i].Lanes {
+			writeSample(w, "petrol_lane_blocked_cars", rm.labelSet("lane", strconv.Itoa(lane.Id), "fuel", string(lane.Fuel)), float64(lane.Blocked))
+		}
+	}
+	writeHeader(w, "petrol_stands_busy", "Stands occupied by a car.", "gauge")
+	for i, rm := range runs {
+		busyStands := make(map[Services.FuelType]int)
+		for _, stand := range snapshots[i].Stands {
+			busyStands[stand.ServingFuel] += busy(stand)
+		}
+		for _, fuel := range Services.FuelTypes {
+			writeSample(w, "petrol_stands_busy", rm.labelSet("fuel", string(fuel)), float64(busyStands[fuel]))
+		}
+	}
+	writeHeader(w, "petrol_register_queue_length", "Cars waiting in the queue of a register.", "gauge")
+	for i, rm := range runs {
+		for _, register := range snapshots[i].Registers {
+			writeSample(w, "petrol_register_queue_length", rm.labelSet("register", strconv.Itoa(register.Id)), float64(register.Queue))
+		}
+	}
+	writeHeader(w, "petrol_registers_busy", "Registers serving a car.", "gauge")
+	for i, rm := range runs {
+		busyRegisters := 0
+		for _, register := range snapshots[i].Registers {
+			busyRegisters += busy(register)
+		}
+		writeSample(w, "petrol_registers_busy", rm.labelSet(), float64(busyRegisters))
+	}
+	writeHeader(w, "petrol_building_queue_length", "Fueled cars waiting for a register.", "gauge")
+	for i, rm := range runs {
+		writeSample(w, "petrol_building_queue_length", rm.labelSet(), float64(snapshots[i].BuildingQueue))
+	}
+	writeHeader(w, "petrol_wash_queue_length", "Cars waiting for a wash bay.", "gauge")
+	for i, rm := range runs {
+		writeSample(w, "petrol_wash_queue_length", rm.labelSet(), float64(snapshots[i].WashQueue))
+	}
+	writeHeader(w, "petrol_wash_bays_busy", "Wash bays washing a car.", "gauge")
+	for i, rm := range runs {
+		busyBays := 0
+		for _, bay := range snapshots[i].WashBays {
+			busyBays += busy(bay)
+		}
+		writeSample(w, "petrol_wash_bays_busy", rm.labelSet(), float64(busyBays))
+	}
+	writeHeader(w, "petrol_arrival_backlog", "Arrived cars not yet assigned to a stand.", "gauge")
+	for i, rm := range runs {
+		writeSample(w, "petrol_arrival_backlog", rm.labelSet(), float64(snapshots[i].ArrivalBacklog))
+	}
+
+	// Histograms
+	histograms := []struct {
+		name, help string
+		values     func(*runMetrics) map[[2]string]*histogram
+	}{
+		{"petrol_queue_time_ms", "Simulated ms served cars waited in the stand or register queue.", func(rm *runMetrics) map[[2]string]*histogram { return rm.queueTimes }},
+		{"petrol_service_time_ms", "Simulated ms served cars spent fueling or paying.", func(rm *runMetrics) map[[2]string]*histogram { return rm.serviceTimes }},
+	}
+	for _, family := range histograms {
+		writeHeader(w, family.name, family.help, "histogram")
+		for _, rm := range runs {
+			rm.mu.Lock()
+			values := family.values(rm)
+			keys := make([][2]string, 0, len(values))
+			for key := range values {
+				keys = append(keys, key)
+			}
+			sort.Slice(keys, func(i, j int) bool {
+				return keys[i][0] < keys[j][0] || (keys[i][0] == keys[j][0] && keys[i][1] < keys[j][1])
+			})
+			for _, key := range keys {
+				h := values[key]
+				for i, bound := range metricBuckets {
+					labels := rm.labelSet("stage", key[0], "fuel", key[1], "le", strconv.FormatFloat(bound, 'g', -1, 64))
+					writeSample(w, family.name+"_bucket", labels, float64(h.counts[i]))
+				}
+				writeSample(w, family.name+"_bucket", rm.labelSet("stage", key[0], "fuel", key[1], "le", "+Inf"), float64(h.count))
+				writeSample(w, family.name+"_sum", rm.labelSet("stage", key[0], "fuel", key[1]), h.sum)
+				writeSample(w, family.name+"_count", rm.labelSet("stage", key[0], "fuel", key[1]), float64(h.count))
+			}
+			rm.mu.Unlock()
+		}
+	}
+}
+
+// observe records a value in the histogram of the stage and fuel
+func observe(histograms map[[2]string]*histogram, stage, fuel string, value float64) {
+	key := [2]string{stage, fuel}
+	if histograms[key] == nil {
+		histograms[key] = &histogram{}
+	}
+	histograms[key].observe(value)
+}
+
+// labelSet formats the run labels followed by the given name value pairs
+func (rm *runMetrics) labelSet(pairs ...string) string {
+	all := append(append([]string(nil), rm.labels...), pairs...)
+	if len(all) == 0 {
+		return ""
+	}
+	parts := make([]string, 0, len(all)/2)
+	for i := 0; i+1 < len(all); i += 2 {
+		parts = append(parts, fmt.Sprintf("%s=%s", all[i], strconv.Quote(all[i+1])))
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+// writeHeader writes the help and type lines of a metric family
+func writeHeader(w io.Writer, name, help, kind string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+// writeSample writes a single sample line
+func writeSample(w io.Writer, name, labels string, value float64) {
+	fmt.Fprintf(w, "%s%s %s\n", name, labels, strconv.FormatFloat(value, 'g', -1, 64))
+}
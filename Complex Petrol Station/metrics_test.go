@@ -0,0 +1,114 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestHistogramObserve(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []float64
+		counts []uint64
+		sum    float64
+	}{
+		{"bucket bounds", []float64{1, 2, 1000}, []uint64{1, 2, 2, 2, 2, 2, 2, 2, 2, 3}, 1003},
+		{"between bounds", []float64{0, 7, 45}, []uint64{1, 1, 1, 2, 2, 3, 3, 3, 3, 3}, 52},
+		{"above all", []float64{1500}, []uint64{0, 0, 0, 0, 0, 0, 0, 0, 0, 0}, 1500},
+	}
+	for _, tt := range tests {
+		var h histogram
+		for _, value := range tt.values {
+			h.observe(value)
+		}
+		if fmt.Sprint(h.counts) != fmt.Sprint(tt.counts) || h.count != uint64(len(tt.values)) || h.sum != tt.sum {
+			t.Errorf("%s: counts %v of %d summing to %v, want %v of %d summing to %v", tt.name,
+				h.counts, h.count, h.sum, tt.counts, len(tt.values), tt.sum)
+		}
+	}
+}
+
+func TestLabelSet(t *testing.T) {
+	tests := []struct {
+		labels []string
+		pairs  []string
+		want   string
+	}{
+		{nil, nil, ""},
+		{[]string{"run", "3"}, nil, `{run="3"}`},
+		{nil, []string{"fuel", "gas"}, `{fuel="gas"}`},
+		{[]string{"run", "3"}, []string{"stand", "1", "fuel", "gas+diesel"}, `{run="3",stand="1",fuel="gas+diesel"}`},
+		{[]string{"config", `a "b"`}, nil, `{config="a \"b\""}`},
+	}
+	for _, tt := range tests {
+		rm := newRunMetrics(nil, tt.labels...)
+		if got := rm.labelSet(tt.pairs...); got != tt.want {
+			t.Errorf("labelSet(%v, %v) = %s, want %s", tt.labels, tt.pairs, got, tt.want)
+		}
+	}
+}
+
+func TestMetricsEndpoint(t *testing.T) {
+	handler := newRunServer(1).handler()
+	if rec := request(handler, "GET", "/metrics"); rec.Code != http.StatusOK || strings.Contains(rec.Body.String(), "{run=") {
+		t.Errorf("metrics without runs = %d %s, want families without samples", rec.Code, rec.Body)
+	}
+	rec := submit(t, handler, `{"seed": 3, "cars": {"count": 12}}`)
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("submit = %d %s, want 202", rec.Code, rec.Body)
+	}
+	location := rec.Header().Get("Location")
+	waitRun(t, handler, location)
+
+	rec = request(handler, "GET", "/metrics")
+	if rec.Code != http.StatusOK || !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/plain; version=0.0.4") {
+		t.Fatalf("metrics = %d %q, want prometheus text", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	types := make(map[string]int)
+	samples := make(map[string]float64)
+	for _, line := range strings.Split(strings.TrimSpace(rec.Body.String()), "\n") {
+		if fields := strings.Fields(line); strings.HasPrefix(line, "# TYPE ") {
+			types[fields[2]]++
+		} else if !strings.HasPrefix(line, "#") {
+			value, err := strconv.ParseFloat(fields[len(fields)-1], 64)
+			if err != nil {
+				t.Fatalf("sample %q: %v", line, err)
+			}
+			samples[strings.Join(fields[:len(fields)-1], " ")] = value
+		}
+	}
+	for name, count := range types {
+		if count != 1 {
+			t.Errorf("%s has %d type lines, want 1", name, count)
+		}
+	}
+	served, observed := 0.0, 0.0
+	for sample, value := range samples {
+		if !strings.Contains(sample, `run="1"`) {
+			t.Errorf("sample %s lacks the run label", sample)
+		}
+		if strings.HasPrefix(sample, "petrol_cars_served_total{") {
+			served += value
+		}
+		if strings.HasPrefix(sample, "petrol_queue_time_ms_count{") && strings.Contains(sample, `stage="stand"`) {
+			observed += value
+		}
+		if strings.HasPrefix(sample, "petrol_queue_time_ms_bucket{") && strings.Contains(sample, `le="+Inf"`) {
+			count := strings.Replace(strings.Replace(sample, "_bucket", "_count", 1), `,le="+Inf"`, "", 1)
+			if samples[count] != value {
+				t.Errorf("%s = %v, want the count %v", sample, value, samples[count])
+			}
+		}
+	}
+	if served != 12 || observed != 12 {
+		t.Errorf("served %v cars and observed %v stand queue times, want 12", served, observed)
+	}
+
+	request(handler, "DELETE", location)
+	if rec := request(handler, "GET", "/metrics"); strings.Contains(rec.Body.String(), `run="1"`) {
+		t.Errorf("metrics of a deleted run are still exposed")
+	}
+}
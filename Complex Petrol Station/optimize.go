@@ -0,0 +1,344 @@
+package main
+
+import (
+	"fmt"
+	"goenv/Services"
+	"gopkg.in/yaml.v2"
+	"io"
+	"os"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Initializations
+
+// OptimizeSpec is a struct for the optimizer configuration
+type OptimizeSpec struct {
+	Costs struct {
+		Gas      float64 `yaml:"gas"`
+		Diesel   float64 `yaml:"diesel"`
+		Lpg      float64 `yaml:"lpg"`
+		Electric float64 `yaml:"electric"`
+		Register float64 `yaml:"register"`
+	} `yaml:"costs"`
+	SLA struct {
+		Metric       string  `yaml:"metric"`        // metric of every fuel group, e.g. p95_queue_time
+		Max          float64 `yaml:"max"`           // upper bound of the metric for each fuel
+		RegistersMax float64 `yaml:"registers_max"` // upper bound for the registers, ignored when zero
+	} `yaml:"sla"`
+	Search       yaml.MapSlice `yaml:"search"` // ranges of gas, diesel, lpg, electric and registers counts
+	Replications int           `yaml:"replications"`
+}
+
+// layoutKeys are the config values the optimizer searches over, in layout order
+var layoutKeys = []struct {
+	name string
+	key  string
+}{
+	{"gas", "stations.gas.count"},
+	{"diesel", "stations.diesel.count"},
+	{"lpg", "stations.lpg.count"},
+	{"electric", "stations.electric.count"},
+	{"registers", "registers.count"},
+}
+
+// slaGroup is a statistics group bound by the sla
+type slaGroup struct {
+	name string
+	max  float64
+}
+
+// layout is a candidate number of stands per fuel and registers
+type layout struct {
+	counts    [5]int
+	cost      float64
+	evaluated bool
+	feasible  bool
+	evidence  []metricSummary
+	violated  []int // layout indexes of the groups breaking the sla
+}
+
+// Commands
+
+// optimizeCommand searches for the cheapest layout meeting the waiting time SLA
+func optimizeCommand(args []string) error {
+	fs := newFlagSet("optimize")
+	var cf configFlags
+	cf.register(fs)
+	specPath := fs.String("spec", "optimize.yaml", "yaml file with costs, sla and search ranges")
+	parallel := fs.Int("parallel", runtime.NumCPU(), "number of simulations running at once")
+	maxEvaluations := fs.Int("max-evaluations", 50, "upper bound on evaluated layouts")
+	out := fs.String("out", "-", "path of the table of evaluated layouts, - for stdout")
+	format := fs.String("format", "markdown", "table format: csv, markdown, json or yaml")
+	quiet := fs.Bool("quiet", false, "do not print progress")
+	prune := fs.Bool("prune", false, "skip layouts an evaluated one shows to break the SLA, faster but may miss the cheapest layout")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	base, err := cf.load()
+	if err != nil {
+		return err
+	}
+	spec, err := loadOptimizeSpec(*specPath)
+	if err != nil {
+		return configError{err}
+	}
+	if _, err = (table{}).encode(*format); err != nil {
+		return configError{err}
+	}
+	if base.Seed == 0 {
+		// Common seeds for all layouts make their comparison fairer
+		base.Seed = time.Now().UnixNano()
+	}
+	candidates, err := spec.candidates(base)
+	if err != nil {
+		return configError{err}
+	}
+	progress := io.Writer(os.Stderr)
+	if *quiet {
+		progress = io.Discard
+	}
+
+	// Evaluating layouts from the cheapest until a winner and a runner-up are found
+	var feasible, infeasible []*layout
+	evaluations := 0
+	for _, candidate := range candidates {
+		if len(feasible) == 2 || evaluations == *maxEvaluations {
+			break
+		}
+		if *prune && dominated(candidate, infeasible) {
+			continue
+		}
+		config := base
+		for i, lk := range layoutKeys {
+			if base.Layout.checkCountKey(lk.key) != nil {
+				continue
+			}
+			if err = config.set(fmt.Sprintf("%s=%d", lk.key, candidate.counts[i])); err != nil {
+				return err
+			}
+		}
+		if err = config.validate(); err != nil {
+			return configError{fmt.Errorf("invalid layout %s: %v", candidate, err)}
+		}
+		fmt.Fprintf(progress, "Evaluating %s (cost %.2f)\n", candidate, candidate.cost)
+		options := replicationOptions{count: spec.Replications, parallel: *parallel, progress: io.Discard}
+		stats, _, err := runReplications(config, options)
+		if err != nil {
+			return err
+		}
+		evaluations++
+		candidate.evaluated = true
+		candidate.evidence, candidate.violated = spec.check(summarize(stats))
+		candidate.feasible = len(candidate.violated) == 0
+		if candidate.feasible {
+			feasible = append(feasible, candidate)
+		} else {
+			infeasible = append(infeasible, candidate)
+		}
+	}
+	if len(feasible) == 0 {
+		return fmt.Errorf("no evaluated layout meets the SLA, widen the search ranges")
+	}
+	fmt.Fprintf(progress, "Cheapest layout meeting the SLA: %s (cost %.2f)\n", feasible[0], feasible[0].cost)
+	return writeTable(spec.table(candidates, feasible), *format, *out)
+}
+
+// Utilities
+
+// loadOptimizeSpec reads and checks the optimizer configuration
+func loadOptimizeSpec(path string) (OptimizeSpec, error) {
+	var spec OptimizeSpec
+	file, err := os.ReadFile(path)
+	if err != nil {
+		return spec, fmt.Errorf("error reading %s file: %v", path, err)
+	}
+	if err = yaml.UnmarshalStrict(file, &spec); err != nil {
+		return spec, fmt.Errorf("error unmarshalling %s file: %v", path, err)
+	}
+	if spec.SLA.Metric == "" {
+		spec.SLA.Metric = "p95_queue_time"
+	}
+	if !isMetric("gas." + spec.SLA.Metric) {
+		return spec, fmt.Errorf("unknown sla metric %q", spec.SLA.Metric)
+	}
+	if spec.SLA.Max <= 0 {
+		return spec, fmt.Errorf("sla.max must be positive")
+	}
+	if spec.Replications == 0 {
+		spec.Replications = 5
+	}
+	if spec.Replications < 2 {
+		return spec, fmt.Errorf("at least 2 replications are needed")
+	}
+	return spec, nil
+}
+
+// candidates lists all layouts of the search ranges ordered by cost
+func (spec OptimizeSpec) candidates(base Config) ([]*layout, error) {
+	// Unlisted counts stay as in the base config
+	fixed := []int{base.Stations.Gas.Count, base.Stations.Diesel.Count, base.Stations.Lpg.Count,
+		base.Stations.Electric.Count, base.Registers.Count}
+	if base.Layout.enabled() {
+		// Islands fix the stands, which are priced by their positions
+		for i, fuel := range Services.FuelTypes {
+			fixed[i] = base.Layout.positions(fuel)
+		}
+	}
+	dimensions := make([]sweepDimension, len(layoutKeys))
+	for i, lk := range layoutKeys {
+		dimensions[i] = sweepDimension{key: lk.name, values: []string{fmt.Sprint(fixed[i])}}
+	}
+	for _, item := range spec.Search {
+		name := fmt.Sprint(item.Key)
+		index := -1
+		for i, lk := range layoutKeys {
+			if lk.name == name {
+				index = i
+			}
+		}
+		if index < 0 {
+			return nil, fmt.Errorf("unknown search dimension %q", name)
+		}
+		if err := base.Layout.checkCountKey(layoutKeys[index].key); err != nil {
+			return nil, fmt.Errorf("search.%s: %v", name, err)
+		}
+		raw := fmt.Sprint(item.Value)
+		if list, ok := item.Value.([]interface{}); ok {
+			parts := make([]string, len(list))
+			for i, element := range list {
+				parts[i] = fmt.Sprint(element)
+			}
+			raw = strings.Join(parts, ",")
+		}
+		values, err := parseSweepValues(raw)
+		if err != nil {
+			return nil, fmt.Errorf("search.%s: %v", name, err)
+		}
+		// A layout without stands of a fuel or without registers would fail validation mid-search
+		for _, value := range values {
+			var count int
+			if _, err := fmt.Sscan(value, &count); err != nil || count < 1 {
+				return nil, fmt.Errorf("search.%s: %q is not a count of at least 1", name, value)
+			}
+		}
+		dimensions[index].values = values
+	}
+	unitCosts := []float64{spec.Costs.Gas, spec.Costs.Diesel, spec.Costs.Lpg, spec.Costs.Electric, spec.Costs.Register}
+	var candidates []*layout
+	for _, values := range combinations(dimensions) {
+		candidate := &layout{}
+		for i, value := range values {
+			if _, err := fmt.Sscan(value, &candidate.counts[i]); err != nil {
+				return nil, fmt.Errorf("search.%s: %q is not a count", layoutKeys[i].name, value)
+			}
+			candidate.cost += float64(candidate.counts[i]) * unitCosts[i]
+		}
+		candidates = append(candidates, candidate)
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].cost != candidates[j].cost {
+			return candidates[i].cost < candidates[j].cost
+		}
+		return candidates[i].units() < candidates[j].units()
+	})
+	return candidates, nil
+}
+
+// check tests the sla against the upper confidence bounds of the replication summaries
+//
+// Returns the evidence of every sla group and the layout indexes of the groups breaking it.
+func (spec OptimizeSpec) check(summaries []metricSummary) ([]metricSummary, []int) {
+	var evidence []metricSummary
+	var violated []int
+	for i, group := range spec.slaGroups() {
+		summary, _ := findSummary(summaries, group.name+"."+spec.SLA.Metric)
+		evidence = append(evidence, summary)
+		if summary.mean+summary.halfWidth > group.max {
+			violated = append(violated, i)
+		}
+	}
+	return evidence, violated
+}
+
+// slaGroups lists the statistics groups bound by the sla with their limits
+func (spec OptimizeSpec) slaGroups() []slaGroup {
+	groups := []slaGroup{{"gas", spec.SLA.Max}, {"diesel", spec.SLA.Max}, {"lpg", spec.SLA.Max}, {"electric", spec.SLA.Max}}
+	if spec.SLA.RegistersMax > 0 {
+		groups = append(groups, slaGroup{"registers", spec.SLA.RegistersMax})
+	}
+	return groups
+}
+
+// table lays out the evaluated layouts with their evidence, marking the winner and runner-up
+func (spec OptimizeSpec) table(candidates, feasible []*layout) table {
+	t := table{columns: []string{"choice"}}
+	for _, lk := range layoutKeys {
+		t.columns = append(t.columns, lk.name)
+	}
+	t.columns = append(t.columns, "cost", "meets_sla")
+	for _, group := range spec.slaGroups() {
+		name := group.name + "." + spec.SLA.Metric
+		t.columns = append(t.columns, name+"_mean", name+"_ci_low", name+"_ci_high")
+	}
+	for _, candidate := range candidates {
+		if !candidate.evaluated {
+			continue
+		}
+		choice := ""
+		if candidate == feasible[0] {
+			choice = "chosen"
+		} else if len(feasible) > 1 && candidate == feasible[1] {
+			choice = "runner-up"
+		}
+		row := []interface{}{choice}
+		for _, count := range candidate.counts {
+			row = append(row, count)
+		}
+		row = append(row, candidate.cost, candidate.feasible)
+		for _, summary := range candidate.evidence {
+			row = append(row, summary.mean, summary.mean-summary.halfWidth, summary.mean+summary.halfWidth)
+		}
+		t.addRow(row...)
+	}
+	return t
+}
+
+// dominated reports whether a layout is bound to fail the sla like an already evaluated one
+//
+// A group breaking the sla is assumed to keep breaking it without more stands of
+// its own fuel or more registers, which the stands wait for before releasing a car.
+func dominated(candidate *layout, infeasible []*layout) bool {
+	registers := len(layoutKeys) - 1
+	for _, failed := range infeasible {
+		if candidate.counts[registers] > failed.counts[registers] {
+			continue
+		}
+		for _, group := range failed.violated {
+			if candidate.counts[group] <= failed.counts[group] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// units returns the total number of stands and registers of a layout
+func (l *layout) units() int {
+	total := 0
+	for _, count := range l.counts {
+		total += count
+	}
+	return total
+}
+
+// String describes the layout counts
+func (l *layout) String() string {
+	parts := make([]string, len(layoutKeys))
+	for i, lk := range layoutKeys {
+		parts[i] = fmt.Sprintf("%s=%d", lk.name, l.counts[i])
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"fmt"
+	"gopkg.in/yaml.v2"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func optimizeSpec(t *testing.T, search string) OptimizeSpec {
+	t.Helper()
+	var spec OptimizeSpec
+	source := "costs: {gas: 100, diesel: 100, lpg: 150, electric: 300, register: 80}\nsearch: " + search
+	if err := yaml.UnmarshalStrict([]byte(source), &spec); err != nil {
+		t.Fatal(err)
+	}
+	return spec
+}
+
+func TestOptimizeCandidates(t *testing.T) {
+	base := defaultConfig()
+	base.Stations.Gas.Count, base.Stations.Diesel.Count, base.Stations.Lpg.Count, base.Stations.Electric.Count = 1, 1, 1, 1
+	base.Registers.Count = 1
+	candidates, err := optimizeSpec(t, "{gas: [1..2], registers: [1, 2]}").candidates(base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		counts [5]int
+		cost   float64
+	}{
+		{[5]int{1, 1, 1, 1, 1}, 730},
+		{[5]int{1, 1, 1, 1, 2}, 810},
+		{[5]int{2, 1, 1, 1, 1}, 830},
+		{[5]int{2, 1, 1, 1, 2}, 910},
+	}
+	if len(candidates) != len(want) {
+		t.Fatalf("got %d candidates, want %d", len(candidates), len(want))
+	}
+	for i, w := range want {
+		if candidates[i].counts != w.counts || candidates[i].cost != w.cost {
+			t.Errorf("candidate %d = %v cost %v, want %v cost %v", i, candidates[i].counts, candidates[i].cost, w.counts, w.cost)
+		}
+	}
+	if _, err := optimizeSpec(t, "{pumps: [1..2]}").candidates(base); err == nil {
+		t.Error("unknown search dimension was accepted")
+	}
+	for _, search := range []string{"{gas: [0..2]}", "{registers: [0, 1]}", "{diesel: [x]}"} {
+		if _, err := optimizeSpec(t, search).candidates(base); err == nil {
+			t.Errorf("search %s without counts of at least 1 was accepted", search)
+		}
+	}
+}
+
+func TestOptimizeCandidatesWithIslands(t *testing.T) {
+	base := defaultConfig()
+	base.Registers.Count = 1
+	base.Layout.Islands = []IslandConfig{
+		{Fuel: "gas", Sides: 2, Positions: 2},
+		{Fuel: "diesel", Sides: 1, Positions: 1},
+	}
+	if _, err := optimizeSpec(t, "{gas: [1..3]}").candidates(base); err == nil {
+		t.Error("searching stand counts replaced by islands was accepted")
+	}
+	candidates, err := optimizeSpec(t, "{registers: [1..2]}").candidates(base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(candidates) != 2 || candidates[0].counts != [5]int{4, 1, 0, 0, 1} || candidates[0].cost != 580 {
+		t.Errorf("first candidate = %v cost %v, want island positions 4 gas and 1 diesel for 580", candidates[0].counts, candidates[0].cost)
+	}
+}
+
+func TestLayoutCheckCountKey(t *testing.T) {
+	islands := LayoutConfig{Islands: []IslandConfig{{Fuel: "gas", Sides: 1, Positions: 1}}}
+	tests := []struct {
+		layout LayoutConfig
+		key    string
+		reject bool
+	}{
+		{LayoutConfig{}, "stations.gas.count", false},
+		{islands, "stations.gas.count", true},
+		{islands, "Stations.LPG.Count", true},
+		{islands, "stations.gas.serve_time_max", false},
+		{islands, "registers.count", false},
+	}
+	for _, tt := range tests {
+		if err := tt.layout.checkCountKey(tt.key); (err != nil) != tt.reject {
+			t.Errorf("checkCountKey(%q) with %d islands = %v, want rejected %v", tt.key, len(tt.layout.Islands), err, tt.reject)
+		}
+	}
+}
+
+func TestDominated(t *testing.T) {
+	// Gas broke the sla with 1 gas stand and 2 registers, registers with 1 register
+	infeasible := []*layout{
+		{counts: [5]int{1, 1, 1, 1, 2}, violated: []int{0}},
+		{counts: [5]int{3, 3, 3, 3, 1}, violated: []int{4}},
+	}
+	tests := []struct {
+		counts [5]int
+		want   bool
+	}{
+		{[5]int{1, 2, 2, 2, 2}, true},
+		{[5]int{1, 1, 1, 1, 1}, true},
+		{[5]int{2, 1, 1, 1, 2}, false},
+		{[5]int{1, 1, 1, 1, 3}, false},
+		{[5]int{2, 2, 2, 2, 1}, true},
+	}
+	for _, tt := range tests {
+		if got := dominated(&layout{counts: tt.counts}, infeasible); got != tt.want {
+			t.Errorf("dominated(%v) = %v, want %v", tt.counts, got, tt.want)
+		}
+	}
+}
+
+func TestLoadOptimizeSpec(t *testing.T) {
+	tests := []struct {
+		name    string
+		source  string
+		metric  string
+		reps    int
+		invalid bool
+	}{
+		{"defaults", "sla: {max: 60}", "p95_queue_time", 5, false},
+		{"explicit", "sla: {metric: avg_queue_time, max: 30}\nreplications: 3", "avg_queue_time", 3, false},
+		{"unknown metric", "sla: {metric: queue_length, max: 30}", "", 0, true},
+		{"no max", "sla: {metric: avg_queue_time}", "", 0, true},
+		{"one replication", "sla: {max: 60}\nreplications: 1", "", 0, true},
+		{"unknown key", "sla: {max: 60}\nbudget: 1000", "", 0, true},
+	}
+	for _, tt := range tests {
+		path := filepath.Join(t.TempDir(), "optimize.yaml")
+		if err := os.WriteFile(path, []byte(tt.source), 0644); err != nil {
+			t.Fatal(err)
+		}
+		spec, err := loadOptimizeSpec(path)
+		if (err != nil) != tt.invalid {
+			t.Errorf("%s: error = %v, want invalid %v", tt.name, err, tt.invalid)
+			continue
+		}
+		if !tt.invalid && (spec.SLA.Metric != tt.metric || spec.Replications != tt.reps) {
+			t.Errorf("%s: metric %q with %d replications, want %q with %d", tt.name, spec.SLA.Metric, spec.Replications, tt.metric, tt.reps)
+		}
+	}
+}
+
+func TestOptimizeCheck(t *testing.T) {
+	var spec OptimizeSpec
+	spec.SLA.Metric, spec.SLA.Max = "p95_queue_time", 60
+	summaries := []metricSummary{
+		{name: "gas.p95_queue_time", mean: 50, halfWidth: 5},
+		{name: "diesel.p95_queue_time", mean: 58, halfWidth: 5},
+		{name: "electric.p95_queue_time", mean: 60, halfWidth: 0},
+		{name: "registers.p95_queue_time", mean: 40, halfWidth: 2},
+	}
+	tests := []struct {
+		name         string
+		registersMax float64
+		groups       int
+		violated     []int
+	}{
+		{"fuels only", 0, 4, []int{1}},
+		{"registers met", 45, 5, []int{1}},
+		{"registers broken", 40, 5, []int{1, 4}},
+	}
+	for _, tt := range tests {
+		spec.SLA.RegistersMax = tt.registersMax
+		if groups := spec.slaGroups(); len(groups) != tt.groups {
+			t.Errorf("%s: %d sla groups, want %d", tt.name, len(groups), tt.groups)
+		}
+		evidence, violated := spec.check(summaries)
+		if len(evidence) != tt.groups {
+			t.Errorf("%s: %d evidence summaries, want %d", tt.name, len(evidence), tt.groups)
+		}
+		if fmt.Sprint(violated) != fmt.Sprint(tt.violated) {
+			t.Errorf("%s: violated = %v, want %v", tt.name, violated, tt.violated)
+		}
+	}
+}
@@ -0,0 +1,285 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"goenv/Services"
+	"gopkg.in/yaml.v2"
+	"path/filepath"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Variables
+
+// version of the simulation, set with -ldflags "-X main.version=..."
+var version = "dev"
+
+// Initializations
+
+// RunMetadata describes the run that produced a set of results
+type RunMetadata struct {
+	Version    string    `yaml:"version" json:"version"`
+	ConfigHash string    `yaml:"config_hash" json:"config_hash"`
+	Seed       int64     `yaml:"seed" json:"seed"`
+	Start      time.Time `yaml:"start" json:"start"`
+	End        time.Time `yaml:"end" json:"end"`
+}
+
+// Results is a struct for output construction of a finished run
+type Results struct {
+	Metadata   RunMetadata `yaml:"metadata" json:"metadata"`
+	FinalStats `yaml:",inline"`
+}
+
+// outputFormat encodes results into one file format
+type outputFormat struct {
+	name        string
+	extension   string
+	contentType string
+	encode      func(Results) ([]byte, error)
+}
+
+// outputFormats lists all supported result formats
+var outputFormats = []outputFormat{
+	{"yaml", ".yaml", "application/yaml", encodeYAML},
+	{"json", ".json", "application/json", encodeJSON},
+	{"csv", ".csv", "text/csv", encodeCSV},
+	{"markdown", ".md", "text/markdown", encodeMarkdown},
+}
+
+// statsGroup is a named group of statistics, e.g. one fuel type
+type statsGroup struct {
+	Name  string
+	Stats StationStats
+}
+
+// groups lists the statistics of every fuel type and the registers in output order
+func (stats FinalStats) groups() []statsGroup {
+	groups := []statsGroup{
+		{"Gas", stats.Gas},
+		{"Diesel", stats.Diesel},
+		{"LPG", stats.LPG},
+		{"Electric", stats.Electric},
+		{"Registers", stats.Registers},
+	}
+	for _, class := range registerClassOrder {
+		if classStats, ok := stats.RegisterClasses[className(class)]; ok {
+			groups = append(groups, statsGroup{className(class), classStats})
+		}
+	}
+	if stats.Wash != nil {
+		groups = append(groups, statsGroup{"Wash", stats.Wash.StationStats})
+	}
+	return groups
+}
+
+// newMetadata describes a run of the config
+func newMetadata(config Config, seed int64, start, end time.Time) RunMetadata {
+	return RunMetadata{
+		Version:    buildVersion(),
+		ConfigHash: config.hash(),
+		Seed:       seed,
+		Start:      start,
+		End:        end,
+	}
+}
+
+// Utilities
+
+// parseFormats parses a comma separated list of result format names
+func parseFormats(names string) ([]outputFormat, error) {
+	var formats []outputFormat
+	for _, name := range strings.Split(names, ",") {
+		format, err := findFormat(strings.TrimSpace(name))
+		if err != nil {
+			return nil, err
+		}
+		formats = append(formats, format)
+	}
+	return formats, nil
+}
+
+// findFormat returns the result format of the given name
+func findFormat(name string) (outputFormat, error) {
+	var names []string
+	for _, format := range outputFormats {
+		if format.name == name || (name == "md" && format.name == "markdown") {
+			return format, nil
+		}
+		names = append(names, format.name)
+	}
+	return outputFormat{}, fmt.Errorf("unknown format %q, use %s", name, strings.Join(names, ", "))
+}
+
+// outputPath returns the file for a format, swapping the extension when writing several formats
+func outputPath(out string, format outputFormat, several bool) string {
+	if !several {
+		return out
+	}
+	return strings.TrimSuffix(out, filepath.Ext(out)) + format.extension
+}
+
+// hash returns a short fingerprint of the configuration, ignoring the seed
+func (config Config) hash() string {
+	config.Seed = 0
+	encoded, err := yaml.Marshal(config)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(encoded)
+	return hex.EncodeToString(sum[:])[:12]
+}
+
+// buildVersion returns the version, falling back to the vcs revision of the build
+func buildVersion() string {
+	if version != "dev" {
+		return version
+	}
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range info.Settings {
+			if setting.Key == "vcs.revision" && len(setting.Value) >= 12 {
+				return version + "-" + setting.Value[:12]
+			}
+		}
+	}
+	return version
+}
+
+// encodeYAML encodes results as yaml
+func encodeYAML(results Results) ([]byte, error) {
+	return yaml.Marshal(&results)
+}
+
+// encodeJSON encodes results as indented json
+func encodeJSON(results Results) ([]byte, error) {
+	encoded, err := json.MarshalIndent(&results, "", "  ")
+	return append(encoded, '\n'), err
+}
+
+// encodeCSV encodes results as csv with one row per statistics group
+func encodeCSV(results Results) ([]byte, error) {
+	var b bytes.Buffer
+	writer := csv.NewWriter(&b)
+	columns := []string{"group", "total_cars", "total_time", "avg_queue_time", "max_queue_time", "p95_queue_time",
+		"version", "config_hash", "seed", "start", "end", "warmup_method", "warmup_cars"}
+	economics := results.Economics
+	if economics != nil {
+		columns = append(columns, "revenue", "gross_margin", "lost_revenue", "cost", "profit")
+	}
+	writer.Write(columns)
+	meta := results.Metadata
+	var warmup WarmupCutoff
+	if results.Warmup != nil {
+		warmup = *results.Warmup
+	}
+	for i, group := range results.groups() {
+		row := []string{
+			group.Name,
+			strconv.Itoa(group.Stats.TotalCars),
+			strconv.Itoa(group.Stats.TotalTime),
+			strconv.Itoa(group.Stats.AvgQueueTime),
+			strconv.Itoa(group.Stats.MaxQueueTime),
+			strconv.Itoa(group.Stats.P95QueueTime),
+			meta.Version,
+			meta.ConfigHash,
+			strconv.FormatInt(meta.Seed, 10),
+			meta.Start.Format(time.RFC3339Nano),
+			meta.End.Format(time.RFC3339Nano),
+			warmup.Method,
+			strconv.Itoa(warmup.Cars),
+		}
+		// Fuel groups carry their own money, the registers row the station totals
+		if economics != nil {
+			switch {
+			case i < len(Services.FuelTypes):
+				fuel := economics.byFuel()[Services.FuelTypes[i]]
+				row = append(row, formatMoney(fuel.Revenue), formatMoney(fuel.GrossMargin), formatMoney(fuel.LostRevenue), "", "")
+			case group.Name == "Registers":
+				row = append(row, formatMoney(economics.Revenue), formatMoney(economics.GrossMargin),
+					formatMoney(economics.LostRevenue), formatMoney(economics.Cost), formatMoney(economics.Profit))
+			default:
+				row = append(row, "", "", "", "", "")
+			}
+		}
+		writer.Write(row)
+	}
+	writer.Flush()
+	return b.Bytes(), writer.Error()
+}
+
+// encodeMarkdown encodes results as markdown tables
+func encodeMarkdown(results Results) ([]byte, error) {
+	var b bytes.Buffer
+	meta := results.Metadata
+	b.WriteString("## Run\n\n| Version | Config hash | Seed | Start | End |\n|---|---|---|---|---|\n")
+	fmt.Fprintf(&b, "| %s | %s | %d | %s | %s |\n\n", meta.Version, meta.ConfigHash, meta.Seed,
+		meta.Start.Format(time.RFC3339), meta.End.Format(time.RFC3339))
+	if results.Warmup != nil {
+		fmt.Fprintf(&b, "Warm-up (%s): first %d cars excluded, counting from %d ms\n\n",
+			results.Warmup.Method, results.Warmup.Cars, results.Warmup.Time)
+	}
+	b.WriteString("## Statistics\n\n| Group | Total cars | Total time | Avg queue time | Max queue time | P95 queue time |\n|---|---:|---:|---:|---:|---:|\n")
+	for _, group := range results.groups() {
+		fmt.Fprintf(&b, "| %s | %d | %d | %d | %d | %d |\n", group.Name, group.Stats.TotalCars,
+			group.Stats.TotalTime, group.Stats.AvgQueueTime, group.Stats.MaxQueueTime, group.Stats.P95QueueTime)
+	}
+	if results.Wash != nil {
+		fmt.Fprintf(&b, "\nWash bays busy %.0f%% of the run\n", results.Wash.Utilization*100)
+	}
+	if len(results.Stands) > 0 {
+		b.WriteString("\n## Stands\n\n| Stand | Fuels | Total cars | Total time | Avg queue time | Cars and time by fuel |\n|---|---|---:|---:|---:|---|\n")
+		for _, s := range results.Stands {
+			var byFuel []string
+			for _, f := range s.ByFuel {
+				byFuel = append(byFuel, fmt.Sprintf("%s %d / %d ms", f.Fuel, f.TotalCars, f.TotalTime))
+			}
+			fmt.Fprintf(&b, "| %d | %s | %d | %d | %d | %s |\n", s.Id, s.Fuels, s.TotalCars, s.TotalTime, s.AvgQueueTime,
+				strings.Join(byFuel, ", "))
+		}
+	}
+	if l := results.Layout; l != nil {
+		b.WriteString("\n## Lanes\n\n| Lane | Island | Side | Fuel | Total cars | Blocked cars | Total blocked time | Max blocked time |\n|---|---:|---:|---|---:|---:|---:|---:|\n")
+		for _, lane := range l.Lanes {
+			fmt.Fprintf(&b, "| %d | %d | %d | %s | %d | %d | %d | %d |\n", lane.Id, lane.Island, lane.Side, lane.Fuel,
+				lane.TotalCars, lane.BlockedCars, lane.TotalBlocked, lane.MaxBlockedTime)
+		}
+		fmt.Fprintf(&b, "\nBlocked cars %d, average blocked time %d ms, longest %d ms\n", l.BlockedCars, l.AvgBlockedTime, l.MaxBlockedTime)
+	}
+	if len(results.Classes) > 0 {
+		b.WriteString("\n## Classes\n\n| Class | Total cars | Avg stand queue time | Max stand queue time | P95 stand queue time | Avg register queue time | Avg total time |\n|---|---:|---:|---:|---:|---:|---:|\n")
+		for _, c := range results.Classes {
+			fmt.Fprintf(&b, "| %s | %d | %d | %d | %d | %d | %d |\n", c.Class, c.TotalCars, c.AvgStandQueueTime,
+				c.MaxStandQueueTime, c.P95StandQueueTime, c.AvgRegisterQueueTime, c.AvgTotalTime)
+		}
+	}
+	if len(results.Payments) > 0 {
+		b.WriteString("\n## Payments\n\n| Method | Total cars | Attempts | Declined | Avg pay time | Max pay time | Avg queue time |\n|---|---:|---:|---:|---:|---:|---:|\n")
+		for _, p := range results.Payments {
+			fmt.Fprintf(&b, "| %s | %d | %d | %d | %d | %d | %d |\n", p.Method, p.TotalCars, p.Attempts, p.Declined,
+				p.AvgPayTime, p.MaxPayTime, p.AvgQueueTime)
+		}
+	}
+	if e := results.Economics; e != nil {
+		b.WriteString("\n## Economics\n\n| Fuel | Cars | Volume | Avg price | Revenue | Gross margin | Balked | Lost revenue |\n|---|---:|---:|---:|---:|---:|---:|---:|\n")
+		for i, fuel := range Services.FuelTypes {
+			f := e.byFuel()[fuel]
+			fmt.Fprintf(&b, "| %s | %d | %.1f | %.3f | %.2f | %.2f | %d | %.2f |\n", results.groups()[i].Name, f.Cars, f.Volume,
+				f.AvgPrice, f.Revenue, f.GrossMargin, f.Balked, f.LostRevenue)
+		}
+		fmt.Fprintf(&b, "\nRevenue %.2f (fuel %.2f, shop %.2f), gross margin %.2f, cost %.2f over %.1f hours, profit %.2f, lost revenue %.2f\n",
+			e.Revenue, e.FuelRevenue, e.ShopRevenue, e.GrossMargin, e.Cost, e.Hours, e.Profit, e.LostRevenue)
+	}
+	return b.Bytes(), nil
+}
+
+// formatMoney formats an amount of money with two decimals
+func formatMoney(amount float64) string {
+	return strconv.FormatFloat(amount, 'f', 2, 64)
+}
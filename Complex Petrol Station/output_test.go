@@ -0,0 +1,112 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"gopkg.in/yaml.v2"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseFormats(t *testing.T) {
+	tests := []struct {
+		names string
+		want  []string
+		valid bool
+	}{
+		{"yaml", []string{"yaml"}, true},
+		{"json, csv", []string{"json", "csv"}, true},
+		{"md,yaml", []string{"markdown", "yaml"}, true},
+		{"yaml,xml", nil, false},
+		{"", nil, false},
+	}
+	for _, tt := range tests {
+		formats, err := parseFormats(tt.names)
+		if (err == nil) != tt.valid {
+			t.Errorf("parseFormats(%q) error = %v, want valid %v", tt.names, err, tt.valid)
+			continue
+		}
+		var got []string
+		for _, format := range formats {
+			got = append(got, format.name)
+		}
+		if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("parseFormats(%q) = %v, want %v", tt.names, got, tt.want)
+		}
+	}
+}
+
+func TestOutputPath(t *testing.T) {
+	csvFormat, _ := findFormat("csv")
+	tests := []struct {
+		out     string
+		several bool
+		want    string
+	}{
+		{"final_stats.yaml", false, "final_stats.yaml"},
+		{"final_stats.yaml", true, "final_stats.csv"},
+		{"out/results", true, "out/results.csv"},
+		{"-", false, "-"},
+	}
+	for _, tt := range tests {
+		if got := outputPath(tt.out, csvFormat, tt.several); got != tt.want {
+			t.Errorf("outputPath(%q, several %v) = %q, want %q", tt.out, tt.several, got, tt.want)
+		}
+	}
+}
+
+func TestConfigHashIgnoresSeed(t *testing.T) {
+	a, b := defaultConfig(), defaultConfig()
+	b.Seed = 42
+	if a.hash() != b.hash() || len(a.hash()) != 12 {
+		t.Errorf("hashes %q and %q differ by the seed only", a.hash(), b.hash())
+	}
+	b.Stations.Gas.Count++
+	if a.hash() == b.hash() {
+		t.Error("hash does not change with the stands")
+	}
+}
+
+func testResults() Results {
+	start := time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC)
+	results := Results{Metadata: newMetadata(defaultConfig(), 7, start, start.Add(time.Second))}
+	results.Gas = StationStats{TotalCars: 10, TotalTime: 40, AvgQueueTime: 3, MaxQueueTime: 9, P95QueueTime: 8}
+	results.Registers = StationStats{TotalCars: 10, TotalTime: 30, AvgQueueTime: 1, MaxQueueTime: 2, P95QueueTime: 2}
+	results.Warmup = &WarmupCutoff{Method: "cars", Cars: 2, Time: 5}
+	return results
+}
+
+func TestEncodeResults(t *testing.T) {
+	results := testResults()
+	var decoded Results
+	encoded, err := encodeJSON(results)
+	if err != nil || json.Unmarshal(encoded, &decoded) != nil || decoded.Gas != results.Gas || decoded.Metadata.Seed != 7 {
+		t.Errorf("json round trip = %+v, %v", decoded.Gas, err)
+	}
+	decoded = Results{}
+	encoded, err = encodeYAML(results)
+	if err != nil || yaml.Unmarshal(encoded, &decoded) != nil || decoded.Registers != results.Registers || *decoded.Warmup != *results.Warmup {
+		t.Errorf("yaml round trip = %+v, %v", decoded.Registers, err)
+	}
+
+	encoded, err = encodeCSV(results)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows, err := csv.NewReader(strings.NewReader(string(encoded))).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 1+len(results.groups()) || rows[0][0] != "group" || strings.Join(rows[1][:6], ",") != "Gas,10,40,3,9,8" {
+		t.Errorf("csv rows = %v", rows)
+	}
+	if header := strings.Join(rows[0], ","); strings.Contains(header, "revenue") {
+		t.Errorf("csv without economics has money columns: %s", header)
+	}
+
+	encoded, err = encodeMarkdown(results)
+	if err != nil || !strings.Contains(string(encoded), "| Gas | 10 | 40 | 3 | 9 | 8 |") {
+		t.Errorf("markdown = %s, %v", encoded, err)
+	}
+}
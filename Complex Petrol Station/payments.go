@@ -0,0 +1,132 @@
+package main
+
+import (
+	"fmt"
+	"goenv/Services"
+	"time"
+)
+
+// Initializations
+
+// PaymentMethodConfig is a struct for the configuration of one payment method
+type PaymentMethodConfig struct {
+	Share       float64 `yaml:"share" json:"share"` // relative share of cars paying with the method
+	TimeMin     int     `yaml:"time_min" json:"time_min"`
+	TimeMax     int     `yaml:"time_max" json:"time_max"`
+	FailureRate float64 `yaml:"failure_rate" json:"failure_rate"` // probability that an attempt is declined
+	Retries     int     `yaml:"retries" json:"retries"`           // attempts repeated after declines
+}
+
+// PaymentsConfig is a struct for the configuration of the payment method mix
+type PaymentsConfig struct {
+	Cash      PaymentMethodConfig `yaml:"cash" json:"cash"`
+	Card      PaymentMethodConfig `yaml:"card" json:"card"`
+	FleetCard PaymentMethodConfig `yaml:"fleet_card" json:"fleet_card"`
+	App       PaymentMethodConfig `yaml:"app" json:"app"`
+}
+
+// PaymentStats is a struct for output construction of the payments with one method
+type PaymentStats struct {
+	Method       string `yaml:"method" json:"method"`
+	TotalCars    int    `yaml:"total_cars" json:"total_cars"`
+	Attempts     int    `yaml:"attempts" json:"attempts"`
+	Declined     int    `yaml:"declined" json:"declined"`
+	TotalTime    int    `yaml:"total_time" json:"total_time"` // ms spent paying, declined attempts included
+	AvgPayTime   int    `yaml:"avg_pay_time" json:"avg_pay_time"`
+	MaxPayTime   int    `yaml:"max_pay_time" json:"max_pay_time"`
+	AvgQueueTime int    `yaml:"avg_queue_time" json:"avg_queue_time"`
+}
+
+// Utilities
+
+// methods maps payment methods to their configuration
+func (payments *PaymentsConfig) methods() map[Services.PaymentMethod]*PaymentMethodConfig {
+	return map[Services.PaymentMethod]*PaymentMethodConfig{
+		Services.Cash:      &payments.Cash,
+		Services.Card:      &payments.Card,
+		Services.FleetCard: &payments.FleetCard,
+		Services.App:       &payments.App,
+	}
+}
+
+// enabled reports whether any payment method has a share
+func (payments PaymentsConfig) enabled() bool {
+	for _, method := range payments.methods() {
+		if method.Share > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// validate checks the payment methods that have a share
+func (payments PaymentsConfig) validate() error {
+	for _, method := range Services.PaymentMethods {
+		setup := payments.methods()[method]
+		name := "payments." + string(method)
+		if setup.Share < 0 || setup.Retries < 0 {
+			return fmt.Errorf("%s share and retries must not be negative", name)
+		}
+		if setup.FailureRate < 0 || setup.FailureRate > 1 {
+			return fmt.Errorf("%s.failure_rate must be between 0 and 1", name)
+		}
+		if setup.Share == 0 {
+			continue
+		}
+		if err := checkRange(name+".time", setup.TimeMin, setup.TimeMax); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setups converts the config to station payment methods, nil when no method has a share
+func (payments PaymentsConfig) setups() map[Services.PaymentMethod]Services.PaymentSetup {
+	if !payments.enabled() {
+		return nil
+	}
+	result := make(map[Services.PaymentMethod]Services.PaymentSetup)
+	for method, setup := range payments.methods() {
+		result[method] = Services.PaymentSetup{Share: setup.Share, MinT: setup.TimeMin, MaxT: setup.TimeMax,
+			FailureRate: setup.FailureRate, Retries: setup.Retries}
+	}
+	return result
+}
+
+// paymentStats computes payment counts and times of every payment method with a share
+func (payments PaymentsConfig) paymentStats(cars []*Services.Car) []PaymentStats {
+	var stats []PaymentStats
+	for _, method := range Services.PaymentMethods {
+		if payments.methods()[method].Share == 0 {
+			continue
+		}
+		s := PaymentStats{Method: string(method)}
+		var totalQueue time.Duration
+		for _, car := range cars {
+			if car.PaymentMethod != method {
+				continue
+			}
+			s.TotalCars++
+			s.Attempts += car.PaymentAttempts
+			s.Declined += car.PaymentAttempts - 1
+			s.TotalTime += int(car.PayTime)
+			s.MaxPayTime = max(s.MaxPayTime, int(car.PayTime))
+			totalQueue += car.RegisterQueueTime
+		}
+		if s.TotalCars != 0 {
+			s.AvgPayTime = s.TotalTime / s.TotalCars
+			s.AvgQueueTime = int(totalQueue) / s.TotalCars
+		}
+		stats = append(stats, s)
+	}
+	return stats
+}
+
+// allPaymentStats returns empty statistics of every payment method, e.g. to list the metric names
+func allPaymentStats() []PaymentStats {
+	var stats []PaymentStats
+	for _, method := range Services.PaymentMethods {
+		stats = append(stats, PaymentStats{Method: string(method)})
+	}
+	return stats
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"goenv/Services"
+	"strings"
+	"testing"
+)
+
+func TestPaymentsValidate(t *testing.T) {
+	method := func(share float64, min, max int, failureRate float64, retries int) PaymentMethodConfig {
+		return PaymentMethodConfig{Share: share, TimeMin: min, TimeMax: max, FailureRate: failureRate, Retries: retries}
+	}
+	tests := []struct {
+		name     string
+		payments PaymentsConfig
+		invalid  string
+	}{
+		{"none", PaymentsConfig{}, ""},
+		{"mix", PaymentsConfig{Cash: method(0.3, 3, 6, 0, 0), Card: method(0.7, 1, 3, 0.05, 2)}, ""},
+		{"unused without times", PaymentsConfig{Card: method(1, 1, 3, 0, 0), App: method(0, 0, 0, 0.5, 1)}, ""},
+		{"negative share", PaymentsConfig{Cash: method(-0.1, 3, 6, 0, 0)}, "payments.cash share"},
+		{"negative retries", PaymentsConfig{App: method(1, 1, 2, 0.1, -1)}, "payments.app share"},
+		{"failure rate", PaymentsConfig{Card: method(1, 1, 3, 1.5, 0)}, "payments.card.failure_rate"},
+		{"time range", PaymentsConfig{FleetCard: method(1, 3, 3, 0, 0)}, "payments.fleet_card.time"},
+	}
+	for _, tt := range tests {
+		err := tt.payments.validate()
+		if (err != nil) != (tt.invalid != "") || err != nil && !strings.Contains(err.Error(), tt.invalid) {
+			t.Errorf("%s: validate = %v, want error about %q", tt.name, err, tt.invalid)
+		}
+	}
+}
+
+func TestPaymentStats(t *testing.T) {
+	payments := PaymentsConfig{Cash: PaymentMethodConfig{Share: 0.5}, Card: PaymentMethodConfig{Share: 0.5}}
+	cars := []*Services.Car{
+		{PaymentMethod: Services.Cash, PaymentAttempts: 1, PayTime: 6, RegisterQueueTime: 2},
+		{PaymentMethod: Services.Card, PaymentAttempts: 3, PayTime: 9, RegisterQueueTime: 10},
+		{PaymentMethod: Services.Card, PaymentAttempts: 1, PayTime: 2, RegisterQueueTime: 0},
+	}
+	want := []PaymentStats{
+		{Method: "cash", TotalCars: 1, Attempts: 1, TotalTime: 6, AvgPayTime: 6, MaxPayTime: 6, AvgQueueTime: 2},
+		{Method: "card", TotalCars: 2, Attempts: 4, Declined: 2, TotalTime: 11, AvgPayTime: 5, MaxPayTime: 9, AvgQueueTime: 5},
+	}
+	got := payments.paymentStats(cars)
+	if len(got) != len(want) {
+		t.Fatalf("got %d methods, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("stats of %s = %+v, want %+v", want[i].Method, got[i], want[i])
+		}
+	}
+	if setups := (PaymentsConfig{}).setups(); setups != nil {
+		t.Errorf("setups without shares = %v, want nil", setups)
+	}
+}
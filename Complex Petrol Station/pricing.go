@@ -0,0 +1,127 @@
+package main
+
+import (
+	"fmt"
+	"goenv/Services"
+	"math"
+)
+
+// Initializations
+
+// PricePeriod is a struct for a price multiplier applied during hours of the day
+type PricePeriod struct {
+	From       int     `yaml:"from" json:"from"` // first hour of the day
+	To         int     `yaml:"to" json:"to"`     // hour the period ends
+	Multiplier float64 `yaml:"multiplier" json:"multiplier"`
+}
+
+// PricingConfig is a struct for the configuration of the pricing strategy
+type PricingConfig struct {
+	Strategy   string        `yaml:"strategy" json:"strategy"`     // fixed, time_of_day, surge or competitor, prices stay fixed when empty
+	Elasticity float64       `yaml:"elasticity" json:"elasticity"` // drop of demand in % per % of price increase
+	TimeOfDay  []PricePeriod `yaml:"time_of_day" json:"time_of_day"`
+	Surge      struct {
+		Threshold int     `yaml:"threshold" json:"threshold"` // queued cars of a fuel before the price rises
+		Step      float64 `yaml:"step" json:"step"`           // price increase per car above the threshold
+		Max       float64 `yaml:"max" json:"max"`             // upper bound of the multiplier
+	} `yaml:"surge" json:"surge"`
+	Competitor struct {
+		Gas      float64 `yaml:"gas" json:"gas"`
+		Diesel   float64 `yaml:"diesel" json:"diesel"`
+		Lpg      float64 `yaml:"lpg" json:"lpg"`
+		Electric float64 `yaml:"electric" json:"electric"`
+		Offset   float64 `yaml:"offset" json:"offset"` // difference from the competitor price
+	} `yaml:"competitor" json:"competitor"`
+}
+
+// pricingStrategies lists the supported pricing strategies
+var pricingStrategies = []string{"fixed", "time_of_day", "surge", "competitor"}
+
+// pricer adjusts fuel prices of a running station by the configured strategy
+type pricer struct {
+	config  PricingConfig
+	base    map[Services.FuelType]float64
+	hour    int
+	station *Services.Station
+}
+
+// newPricer creates the pricing strategy of the config for the station, nil when prices are fixed
+func newPricer(config Config, station *Services.Station) Services.Pricer {
+	if config.Pricing.Strategy == "" {
+		return nil
+	}
+	p := &pricer{config: config.Pricing, base: make(map[Services.FuelType]float64), hour: config.Economics.Hour, station: station}
+	for fuel, pricing := range config.Economics.pricing() {
+		p.base[fuel] = pricing.Price
+	}
+	return p
+}
+
+// Utilities
+
+// Price returns the current price per unit of the fuel
+func (p *pricer) Price(fuel Services.FuelType) float64 {
+	base := p.base[fuel]
+	switch p.config.Strategy {
+	case "time_of_day":
+		hour := int(p.station.Elapsed()/int64(p.hour)) % 24
+		for _, period := range p.config.TimeOfDay {
+			if hour >= period.From && hour < period.To {
+				return base * period.Multiplier
+			}
+		}
+	case "surge":
+		surge := p.config.Surge
+		if queued := p.station.FuelQueue(fuel); queued > surge.Threshold {
+			return base * math.Min(1+surge.Step*float64(queued-surge.Threshold), surge.Max)
+		}
+	case "competitor":
+		if competitor := p.competitor()[fuel]; competitor > 0 {
+			return math.Max(competitor+p.config.Competitor.Offset, 0)
+		}
+	}
+	return base
+}
+
+// Demand returns the constant elasticity demand at the price relative to the base price
+func (p *pricer) Demand(fuel Services.FuelType, price float64) float64 {
+	base := p.base[fuel]
+	if base <= 0 || price <= 0 {
+		return 1
+	}
+	return math.Pow(price/base, -p.config.Elasticity)
+}
+
+// competitor returns the competitor price of every fuel type
+func (p *pricer) competitor() map[Services.FuelType]float64 {
+	c := p.config.Competitor
+	return map[Services.FuelType]float64{
+		Services.Gas:      c.Gas,
+		Services.Diesel:   c.Diesel,
+		Services.LPG:      c.Lpg,
+		Services.Electric: c.Electric,
+	}
+}
+
+// validate checks that the strategy is known and its parameters usable
+func (pricing PricingConfig) validate() error {
+	switch pricing.Strategy {
+	case "", "fixed", "competitor":
+	case "time_of_day":
+		for _, period := range pricing.TimeOfDay {
+			if period.From < 0 || period.To > 24 || period.From >= period.To || period.Multiplier <= 0 {
+				return fmt.Errorf("pricing.time_of_day periods need 0 <= from < to <= 24 and a positive multiplier")
+			}
+		}
+	case "surge":
+		if pricing.Surge.Threshold < 0 || pricing.Surge.Step < 0 || pricing.Surge.Max < 1 {
+			return fmt.Errorf("pricing.surge needs a non-negative threshold and step and a max of at least 1")
+		}
+	default:
+		return fmt.Errorf("unknown pricing.strategy %q, use one of %v", pricing.Strategy, pricingStrategies)
+	}
+	if pricing.Elasticity < 0 {
+		return fmt.Errorf("pricing.elasticity must not be negative")
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"goenv/Services"
+	"math"
+	"strings"
+	"testing"
+)
+
+// testPricer creates a pricer with a gas base price of 2 for a station whose gas stands queue the cars
+func testPricer(t *testing.T, pricing PricingConfig, queued int) Services.Pricer {
+	t.Helper()
+	config := defaultConfig()
+	config.Pricing = pricing
+	config.Economics.Fuels.Gas.Price = 2
+	stand := Services.NewFuelStand(1, Services.Gas, queued)
+	for i := 0; i < queued; i++ {
+		stand.Queue.Push(&Services.Car{ID: i, Fuel: Services.Gas})
+	}
+	return newPricer(config, &Services.Station{Stands: []*Services.FuelStand{stand}})
+}
+
+func TestPricerPrice(t *testing.T) {
+	surge := PricingConfig{Strategy: "surge"}
+	surge.Surge.Threshold, surge.Surge.Step, surge.Surge.Max = 2, 0.1, 1.25
+	competitor := PricingConfig{Strategy: "competitor"}
+	competitor.Competitor.Gas, competitor.Competitor.Offset = 1.9, -0.05
+	below := competitor
+	below.Competitor.Offset = -3
+	tests := []struct {
+		name    string
+		pricing PricingConfig
+		queued  int
+		fuel    Services.FuelType
+		want    float64
+	}{
+		{"fixed", PricingConfig{Strategy: "fixed"}, 0, Services.Gas, 2},
+		{"whole day", PricingConfig{Strategy: "time_of_day", TimeOfDay: []PricePeriod{{From: 0, To: 24, Multiplier: 1.1}}}, 0, Services.Gas, 2.2},
+		{"no period", PricingConfig{Strategy: "time_of_day"}, 0, Services.Gas, 2},
+		{"surge at threshold", surge, 2, Services.Gas, 2},
+		{"surge above threshold", surge, 4, Services.Gas, 2.4},
+		{"surge capped", surge, 9, Services.Gas, 2.5},
+		{"surge of another fuel", surge, 9, Services.Diesel, 0},
+		{"competitor", competitor, 0, Services.Gas, 1.85},
+		{"competitor floored", below, 0, Services.Gas, 0},
+		{"no competitor price", competitor, 0, Services.Diesel, 0},
+	}
+	for _, tt := range tests {
+		if got := testPricer(t, tt.pricing, tt.queued).Price(tt.fuel); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("%s: price = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+	if p := newPricer(defaultConfig(), nil); p != nil {
+		t.Errorf("pricer without strategy = %v, want nil", p)
+	}
+}
+
+func TestPricerDemand(t *testing.T) {
+	tests := []struct {
+		elasticity float64
+		fuel       Services.FuelType
+		price      float64
+		want       float64
+	}{
+		{0, Services.Gas, 4, 1},
+		{1, Services.Gas, 2, 1},
+		{1, Services.Gas, 4, 0.5},
+		{2, Services.Gas, 1, 4},
+		{1.5, Services.Gas, 0, 1},
+		{1.5, Services.Diesel, 3, 1},
+	}
+	for _, tt := range tests {
+		p := testPricer(t, PricingConfig{Strategy: "fixed", Elasticity: tt.elasticity}, 0)
+		if got := p.Demand(tt.fuel, tt.price); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("demand of %s at %v with elasticity %v = %v, want %v", tt.fuel, tt.price, tt.elasticity, got, tt.want)
+		}
+	}
+}
+
+func TestPricingValidate(t *testing.T) {
+	surge := func(threshold int, step, max float64) PricingConfig {
+		p := PricingConfig{Strategy: "surge"}
+		p.Surge.Threshold, p.Surge.Step, p.Surge.Max = threshold, step, max
+		return p
+	}
+	period := func(from, to int, multiplier float64) PricingConfig {
+		return PricingConfig{Strategy: "time_of_day", TimeOfDay: []PricePeriod{{From: from, To: to, Multiplier: multiplier}}}
+	}
+	tests := []struct {
+		name    string
+		pricing PricingConfig
+		invalid string
+	}{
+		{"none", PricingConfig{}, ""},
+		{"competitor", PricingConfig{Strategy: "competitor"}, ""},
+		{"evening", period(17, 24, 1.2), ""},
+		{"wrapping period", period(22, 6, 1.2), "pricing.time_of_day"},
+		{"late period", period(20, 25, 1.2), "pricing.time_of_day"},
+		{"free period", period(0, 6, 0), "pricing.time_of_day"},
+		{"surge", surge(3, 0.05, 1.3), ""},
+		{"surge discount", surge(3, 0.05, 0.9), "pricing.surge"},
+		{"negative step", surge(3, -0.05, 1.3), "pricing.surge"},
+		{"unknown", PricingConfig{Strategy: "auction"}, "unknown pricing.strategy"},
+		{"negative elasticity", PricingConfig{Strategy: "fixed", Elasticity: -1}, "pricing.elasticity"},
+	}
+	for _, tt := range tests {
+		err := tt.pricing.validate()
+		if (err != nil) != (tt.invalid != "") || err != nil && !strings.Contains(err.Error(), tt.invalid) {
+			t.Errorf("%s: validate = %v, want error about %q", tt.name, err, tt.invalid)
+		}
+	}
+}
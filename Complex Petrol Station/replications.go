@@ -0,0 +1,155 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"time"
+)
+
+// Initializations
+
+// replicationOptions controls how many independent replications are run
+type replicationOptions struct {
+	count           int     // initial number of replications
+	parallel        int     // replications running at once
+	targetMetric    string  // metric whose confidence interval should be narrowed
+	targetHalfWidth float64 // wanted half-width of the target metric interval
+	max             int     // upper bound on replications when aiming for a target
+	progress        io.Writer
+}
+
+// metricSummary is the estimate of a single metric over replications
+type metricSummary struct {
+	name      string
+	n         int
+	mean      float64
+	sd        float64
+	halfWidth float64
+}
+
+// tTable holds two sided 95% critical values of Student's t for 1 to 30 degrees of freedom
+var tTable = []float64{
+	12.706, 4.303, 3.182, 2.776, 2.571, 2.447, 2.365, 2.306, 2.262, 2.228,
+	2.201, 2.179, 2.160, 2.145, 2.131, 2.120, 2.110, 2.101, 2.093, 2.086,
+	2.080, 2.074, 2.069, 2.064, 2.060, 2.056, 2.052, 2.048, 2.045, 2.042,
+}
+
+// Routines
+
+// runReplications runs independent replications of the config with derived seeds
+//
+// With a target metric, further replications are run until the half-width of
+// its 95% confidence interval drops below the target or the maximum is reached.
+func runReplications(config Config, options replicationOptions) ([]FinalStats, int64, error) {
+	base := config.Seed
+	if base == 0 {
+		base = time.Now().UnixNano()
+	}
+	seeds := newSeedStream(base)
+	var stats []FinalStats
+	batch := options.count
+	for batch > 0 {
+		var runs []*batchRun
+		for i := 0; i < batch; i++ {
+			run := &batchRun{label: fmt.Sprintf("replication %d", len(stats)+i+1), config: config}
+			run.config.Seed = seeds.next()
+			runs = append(runs, run)
+		}
+		if err := runBatch(runs, options.parallel, options.progress); err != nil {
+			return nil, base, err
+		}
+		for _, run := range runs {
+			stats = append(stats, run.stats)
+		}
+		batch = 0
+		if options.targetMetric != "" {
+			// Metrics depend on the config, economics or a wash only show up when set
+			summary, err := findSummary(summarize(stats), options.targetMetric)
+			if err != nil {
+				return nil, base, configError{fmt.Errorf("target %q is not a metric of this config", options.targetMetric)}
+			}
+			fmt.Fprintf(options.progress, "%s half-width %.3f after %d replications\n", summary.name, summary.halfWidth, summary.n)
+			if summary.halfWidth > options.targetHalfWidth && len(stats) < options.max {
+				batch = min(options.parallel, options.max-len(stats))
+			}
+		}
+	}
+	return stats, base, nil
+}
+
+// Utilities
+
+// summarize estimates mean, standard deviation and 95% confidence interval of every metric
+func summarize(stats []FinalStats) []metricSummary {
+	if len(stats) == 0 {
+		return nil
+	}
+	var summaries []metricSummary
+	for i, m := range stats[0].metrics() {
+		values := make([]float64, len(stats))
+		for j, s := range stats {
+			values[j] = s.metrics()[i].value
+		}
+		mean, sd, halfWidth := confidenceInterval(values)
+		summaries = append(summaries, metricSummary{name: m.name, n: len(values), mean: mean, sd: sd, halfWidth: halfWidth})
+	}
+	return summaries
+}
+
+// confidenceInterval returns the mean, sample standard deviation and 95% half-width of values
+func confidenceInterval(values []float64) (float64, float64, float64) {
+	n := float64(len(values))
+	if n == 0 {
+		return 0, 0, 0
+	}
+	mean := 0.0
+	for _, value := range values {
+		mean += value
+	}
+	mean /= n
+	if n < 2 {
+		return mean, 0, math.Inf(1)
+	}
+	variance := 0.0
+	for _, value := range values {
+		variance += (value - mean) * (value - mean)
+	}
+	sd := math.Sqrt(variance / (n - 1))
+	return mean, sd, tQuantile(len(values)-1) * sd / math.Sqrt(n)
+}
+
+// tQuantile returns the two sided 95% critical value of Student's t
+func tQuantile(df int) float64 {
+	if df < 1 {
+		return math.Inf(1)
+	}
+	if df <= len(tTable) {
+		return tTable[df-1]
+	}
+	// Cornish-Fisher expansion around the normal quantile
+	z := 1.959964
+	d := float64(df)
+	return z + (z*z*z+z)/(4*d) + (5*math.Pow(z, 5)+16*z*z*z+3*z)/(96*d*d)
+}
+
+// findSummary returns the summary of the named metric
+func findSummary(summaries []metricSummary, name string) (metricSummary, error) {
+	for _, summary := range summaries {
+		if summary.name == name {
+			return summary, nil
+		}
+	}
+	return metricSummary{}, fmt.Errorf("unknown metric %q", name)
+}
+
+// summaryTable lays out metric summaries with the run metadata
+func summaryTable(summaries []metricSummary, meta RunMetadata) table {
+	t := table{columns: []string{"metric", "n", "mean", "sd", "ci_low", "ci_high", "half_width",
+		"version", "config_hash", "base_seed"}}
+	for _, s := range summaries {
+		t.addRow(s.name, s.n, s.mean, s.sd, s.mean-s.halfWidth, s.mean+s.halfWidth, s.halfWidth,
+			meta.Version, meta.ConfigHash, meta.Seed)
+	}
+	return t
+}
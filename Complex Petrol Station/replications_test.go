@@ -0,0 +1,108 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestConfidenceInterval(t *testing.T) {
+	tests := []struct {
+		name                string
+		values              []float64
+		mean, sd, halfWidth float64
+	}{
+		{"empty", nil, 0, 0, 0},
+		{"single", []float64{4}, 4, 0, math.Inf(1)},
+		{"pair", []float64{1, 3}, 2, math.Sqrt2, 12.706},
+		{"constant", []float64{5, 5, 5, 5}, 5, 0, 0},
+		{"five", []float64{2, 4, 4, 4, 6}, 4, math.Sqrt(2), 2.776 * math.Sqrt(2) / math.Sqrt(5)},
+	}
+	for _, tt := range tests {
+		mean, sd, halfWidth := confidenceInterval(tt.values)
+		if math.Abs(mean-tt.mean) > 1e-9 || math.Abs(sd-tt.sd) > 1e-9 {
+			t.Errorf("%s: mean, sd = %v, %v, want %v, %v", tt.name, mean, sd, tt.mean, tt.sd)
+		}
+		if math.IsInf(tt.halfWidth, 1) != math.IsInf(halfWidth, 1) || !math.IsInf(halfWidth, 1) && math.Abs(halfWidth-tt.halfWidth) > 1e-9 {
+			t.Errorf("%s: half-width = %v, want %v", tt.name, halfWidth, tt.halfWidth)
+		}
+	}
+}
+
+func TestTQuantile(t *testing.T) {
+	tests := []struct {
+		df   int
+		want float64
+	}{
+		{0, math.Inf(1)},
+		{1, 12.706},
+		{10, 2.228},
+		{30, 2.042},
+		{60, 2.000},
+		{1000, 1.962},
+	}
+	for _, tt := range tests {
+		got := tQuantile(tt.df)
+		if math.IsInf(tt.want, 1) != math.IsInf(got, 1) || !math.IsInf(got, 1) && math.Abs(got-tt.want) > 0.001 {
+			t.Errorf("tQuantile(%d) = %v, want %v", tt.df, got, tt.want)
+		}
+	}
+	// The expansion continues the table without a jump
+	if tQuantile(31) >= tQuantile(30) || tQuantile(31) < 2.03 {
+		t.Errorf("tQuantile(31) = %v, want just below tQuantile(30) = %v", tQuantile(31), tQuantile(30))
+	}
+}
+
+func TestSeedStream(t *testing.T) {
+	first, second, other := newSeedStream(42), newSeedStream(42), newSeedStream(43)
+	differs := false
+	for i := 0; i < 10; i++ {
+		seed := first.next()
+		if seed == 0 {
+			t.Errorf("seed %d is zero", i)
+		}
+		if again := second.next(); again != seed {
+			t.Errorf("seed %d = %d and %d for the same base", i, seed, again)
+		}
+		if other.next() != seed {
+			differs = true
+		}
+	}
+	if !differs {
+		t.Errorf("seeds of bases 42 and 43 are identical")
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	if summaries := summarize(nil); summaries != nil {
+		t.Errorf("summarize(nil) = %v, want nil", summaries)
+	}
+	stats := []FinalStats{
+		{Gas: StationStats{TotalCars: 10, AvgQueueTime: 2}},
+		{Gas: StationStats{TotalCars: 14, AvgQueueTime: 4}},
+	}
+	summaries := summarize(stats)
+	if len(summaries) != len(stats[0].metrics()) {
+		t.Errorf("summarize returned %d summaries, want one per metric %d", len(summaries), len(stats[0].metrics()))
+	}
+	tests := []struct {
+		name string
+		mean float64
+	}{
+		{"gas.total_cars", 12},
+		{"gas.avg_queue_time", 3},
+		{"diesel.total_cars", 0},
+	}
+	for _, tt := range tests {
+		summary, err := findSummary(summaries, tt.name)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if summary.n != 2 || summary.mean != tt.mean {
+			t.Errorf("%s: n, mean = %d, %v, want 2, %v", tt.name, summary.n, summary.mean, tt.mean)
+		}
+	}
+	if _, err := findSummary(summaries, "gas.unknown"); err == nil {
+		t.Errorf("findSummary(gas.unknown) error = nil, want unknown metric")
+	}
+}
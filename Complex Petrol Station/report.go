@@ -0,0 +1,252 @@
+package main
+
+import (
+	"fmt"
+	"goenv/Services"
+	"gopkg.in/yaml.v2"
+	"html/template"
+	"math"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Variables
+
+// Report histograms
+
+var histogramBins = 12
+
+// Initializations
+
+// reportData collects what the html report is built from during a run
+type reportData struct {
+	mu            sync.Mutex
+	samples       *sampler
+	standWaits    map[Services.FuelType][]int
+	registerWaits []int
+}
+
+// newReportData creates an empty report collection using the sampler for queue lengths
+func newReportData(samples *sampler) *reportData {
+	return &reportData{samples: samples, standWaits: make(map[Services.FuelType][]int)}
+}
+
+// chartSeries is a single line of a line chart
+type chartSeries struct {
+	label  string
+	values []float64
+}
+
+// Routines
+
+// collectCars records the waiting times of cars passing from exit to the returned queue
+func (rd *reportData) collectCars(exit <-chan *Services.Car) <-chan *Services.Car {
+	forwarded := make(chan *Services.Car)
+	go func() {
+		defer close(forwarded)
+		for car := range exit {
+			rd.mu.Lock()
+			rd.standWaits[car.Fuel] = append(rd.standWaits[car.Fuel], int(car.StandQueueTime))
+			rd.registerWaits = append(rd.registerWaits, int(car.RegisterQueueTime))
+			rd.mu.Unlock()
+			forwarded <- car
+		}
+	}()
+	return forwarded
+}
+
+// Utilities
+
+// writeReport writes a self-contained html report of a finished run
+func writeReport(path string, config Config, results Results, data *reportData) error {
+	data.mu.Lock()
+	defer data.mu.Unlock()
+	configYAML, err := yaml.Marshal(config)
+	if err != nil {
+		return err
+	}
+	page := struct {
+		Results   Results
+		Groups    []statsGroup
+		Config    string
+		Stands    []template.HTML
+		Histogram []template.HTML
+		Registers template.HTML
+	}{Results: results, Groups: results.groups(), Config: string(configYAML)}
+
+	// Queue length over time per stand
+	samples := data.samples.snapshots()
+	times := make([]float64, len(samples))
+	for i, sample := range samples {
+		times[i] = float64(sample.Time)
+	}
+	if len(samples) > 0 {
+		for i, stand := range samples[len(samples)-1].Stands {
+			queue := make([]float64, len(samples))
+			for j, sample := range samples {
+				if i < len(sample.Stands) {
+					queue[j] = float64(sample.Stands[i].Queue)
+				}
+			}
+			title := fmt.Sprintf("Stand %d (%s) queue length", stand.Id, standFuels(stand))
+			page.Stands = append(page.Stands, lineChartSVG(title, "ms", times, []chartSeries{{"queue", queue}}))
+		}
+	}
+	// Waiting time histograms per fuel
+	for _, fuel := range Services.FuelTypes {
+		if waits := data.standWaits[fuel]; len(waits) > 0 {
+			page.Histogram = append(page.Histogram, histogramSVG(fmt.Sprintf("%s waiting time (ms)", fuel), waits))
+		}
+	}
+	if len(data.registerWaits) > 0 {
+		page.Histogram = append(page.Histogram, histogramSVG("Register waiting time (ms)", data.registerWaits))
+	}
+	// Final register utilization
+	if len(samples) > 0 {
+		var labels []string
+		var values []float64
+		for _, register := range samples[len(samples)-1].Registers {
+			labels = append(labels, fmt.Sprintf("Register %d", register.Id))
+			values = append(values, register.Utilization*100)
+		}
+		page.Registers = barChartSVG("Register utilization (%)", labels, values, 100)
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return reportTemplate.Execute(file, page)
+}
+
+// lineChartSVG draws series sharing the x values as an inline svg line chart
+func lineChartSVG(title, unit string, xs []float64, series []chartSeries) template.HTML {
+	const width, height, pad = 640.0, 220.0, 40.0
+	maxX, maxY := 1.0, 1.0
+	for _, x := range xs {
+		maxX = math.Max(maxX, x)
+	}
+	for _, s := range series {
+		for _, y := range s.values {
+			maxY = math.Max(maxY, y)
+		}
+	}
+	var b strings.Builder
+	svgHeader(&b, title, width, height)
+	svgAxes(&b, width, height, pad, fmt.Sprintf("%.0f %s", maxX, unit), fmt.Sprintf("%.0f", maxY))
+	for i, s := range series {
+		var points []string
+		for j, y := range s.values {
+			px := pad + xs[j]/maxX*(width-2*pad)
+			py := height - pad - y/maxY*(height-2*pad)
+			points = append(points, fmt.Sprintf("%.1f,%.1f", px, py))
+		}
+		fmt.Fprintf(&b, `<polyline fill="none" stroke="%s" stroke-width="1.5" points="%s"><title>%s</title></polyline>`,
+			chartColor(i), strings.Join(points, " "), template.HTMLEscapeString(s.label))
+	}
+	b.WriteString("</svg>")
+	return template.HTML(b.String())
+}
+
+// histogramSVG draws the distribution of values as an inline svg histogram
+func histogramSVG(title string, values []int) template.HTML {
+	maxValue := 1
+	for _, value := range values {
+		maxValue = max(maxValue, value)
+	}
+	binWidth := int(math.Ceil(float64(maxValue+1) / float64(histogramBins)))
+	counts := make([]float64, histogramBins)
+	for _, value := range values {
+		counts[min(value/binWidth, histogramBins-1)]++
+	}
+	labels := make([]string, histogramBins)
+	maxCount := 0.0
+	for i := range labels {
+		labels[i] = fmt.Sprintf("%d-%d", i*binWidth, (i+1)*binWidth-1)
+		maxCount = math.Max(maxCount, counts[i])
+	}
+	return barChartSVG(title, labels, counts, maxCount)
+}
+
+// barChartSVG draws labelled values as an inline svg bar chart
+func barChartSVG(title string, labels []string, values []float64, maxValue float64) template.HTML {
+	const width, height, pad = 640.0, 220.0, 40.0
+	if maxValue <= 0 {
+		maxValue = 1
+	}
+	var b strings.Builder
+	svgHeader(&b, title, width, height)
+	svgAxes(&b, width, height, pad, "", fmt.Sprintf("%.0f", maxValue))
+	slot := (width - 2*pad) / float64(max(len(values), 1))
+	for i, value := range values {
+		barHeight := value / maxValue * (height - 2*pad)
+		x := pad + float64(i)*slot
+		fmt.Fprintf(&b, `<rect x="%.1f" y="%.1f" width="%.1f" height="%.1f" fill="%s"><title>%s: %.1f</title></rect>`,
+			x+slot*0.1, height-pad-barHeight, slot*0.8, barHeight, chartColor(0), template.HTMLEscapeString(labels[i]), value)
+		fmt.Fprintf(&b, `<text x="%.1f" y="%.1f" font-size="9" text-anchor="middle">%s</text>`,
+			x+slot/2, height-pad+12, template.HTMLEscapeString(labels[i]))
+	}
+	b.WriteString("</svg>")
+	return template.HTML(b.String())
+}
+
+// svgHeader opens an svg element with a title
+func svgHeader(b *strings.Builder, title string, width, height float64) {
+	fmt.Fprintf(b, `<svg xmlns="http://www.w3.org/2000/svg" width="%.0f" height="%.0f" viewBox="0 0 %.0f %.0f">`, width, height, width, height)
+	fmt.Fprintf(b, `<text x="%.0f" y="16" font-size="13" text-anchor="middle">%s</text>`, width/2, template.HTMLEscapeString(title))
+}
+
+// svgAxes draws the chart axes with their maximum labels
+func svgAxes(b *strings.Builder, width, height, pad float64, maxX, maxY string) {
+	fmt.Fprintf(b, `<path d="M%.0f %.0f V%.0f H%.0f" stroke="#333" fill="none"/>`, pad, pad, height-pad, width-pad)
+	fmt.Fprintf(b, `<text x="%.0f" y="%.0f" font-size="10" text-anchor="end">%s</text>`, pad-4, pad+4, maxY)
+	fmt.Fprintf(b, `<text x="%.0f" y="%.0f" font-size="10" text-anchor="end">0</text>`, pad-4, height-pad)
+	fmt.Fprintf(b, `<text x="%.0f" y="%.0f" font-size="10" text-anchor="end">%s</text>`, width-pad, height-pad+26, maxX)
+}
+
+// chartColor returns the color of the i-th series
+func chartColor(i int) string {
+	colors := []string{"#1f77b4", "#ff7f0e", "#2ca02c", "#d62728", "#9467bd", "#8c564b"}
+	return colors[i%len(colors)]
+}
+
+// reportTemplate is the layout of the html report
+var reportTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Petrol station simulation report</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #222; }
+table { border-collapse: collapse; margin-bottom: 1em; }
+th, td { border: 1px solid #ccc; padding: 4px 10px; text-align: right; }
+th:first-child, td:first-child { text-align: left; }
+pre { background: #f4f4f4; padding: 1em; }
+svg { display: block; margin: 1em 0; }
+</style>
+</head>
+<body>
+<h1>Petrol station simulation report</h1>
+<p>Version {{.Results.Metadata.Version}}, config {{.Results.Metadata.ConfigHash}}, seed {{.Results.Metadata.Seed}},
+run from {{.Results.Metadata.Start.Format "2006-01-02 15:04:05"}} to {{.Results.Metadata.End.Format "2006-01-02 15:04:05"}}</p>
+{{with .Results.Warmup}}<p>Warm-up ({{.Method}}): first {{.Cars}} cars excluded, counting from {{.Time}} ms</p>{{end}}
+<h2>Statistics</h2>
+<table>
+<tr><th>Group</th><th>Total cars</th><th>Total time</th><th>Avg queue time</th><th>Max queue time</th><th>P95 queue time</th></tr>
+{{range .Groups}}<tr><td>{{.Name}}</td><td>{{.Stats.TotalCars}}</td><td>{{.Stats.TotalTime}}</td><td>{{.Stats.AvgQueueTime}}</td><td>{{.Stats.MaxQueueTime}}</td><td>{{.Stats.P95QueueTime}}</td></tr>
+{{end}}</table>
+<h2>Queue length over time</h2>
+{{range .Stands}}{{.}}
+{{end}}
+<h2>Waiting times</h2>
+{{range .Histogram}}{{.}}
+{{end}}
+<h2>Register utilization</h2>
+{{.Registers}}
+<h2>Configuration</h2>
+<pre>{{.Config}}</pre>
+</body>
+</html>
+`))
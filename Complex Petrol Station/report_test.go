@@ -0,0 +1,68 @@
+package main
+
+import (
+	"goenv/Services"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestHistogramSVGBins(t *testing.T) {
+	defer func(bins int) { histogramBins = bins }(histogramBins)
+	histogramBins = 3
+	// Values up to 8 give bins of width 3
+	svg := string(histogramSVG("Waits <ms>", []int{0, 1, 2, 3, 8, 8, 8}))
+	bars := regexp.MustCompile(`<title>([^<]*): ([0-9.]+)</title>`).FindAllStringSubmatch(svg, -1)
+	want := [][2]string{{"0-2", "3.0"}, {"3-5", "1.0"}, {"6-8", "3.0"}}
+	if len(bars) != len(want) {
+		t.Fatalf("got %d bars, want %d in %s", len(bars), len(want), svg)
+	}
+	for i, bar := range bars {
+		if bar[1] != want[i][0] || bar[2] != want[i][1] {
+			t.Errorf("bar %d = %s with %s, want %s with %s", i, bar[1], bar[2], want[i][0], want[i][1])
+		}
+	}
+	if !strings.Contains(svg, "Waits &lt;ms&gt;") {
+		t.Error("title is not escaped")
+	}
+}
+
+func TestLineChartSVG(t *testing.T) {
+	svg := string(lineChartSVG("Queue", "ms", []float64{0, 50, 100}, []chartSeries{{"queue", []float64{0, 2, 4}}}))
+	// The last point is at the far right and the top of the plot area
+	if !strings.Contains(svg, `points="40.0,180.0 320.0,110.0 600.0,40.0"`) {
+		t.Errorf("unexpected points in %s", svg)
+	}
+}
+
+func TestWriteReport(t *testing.T) {
+	config := defaultConfig()
+	samples := newSampler(10)
+	samples.samples = []Services.Snapshot{
+		{Time: 0, Stands: []Services.QueueState{{Id: 0, Fuel: Services.Gas, Serving: -1}}, Registers: []Services.QueueState{{Id: 0, Serving: -1}}},
+		{Time: 10, Stands: []Services.QueueState{{Id: 0, Fuel: Services.Gas, Queue: 2, Serving: 1}}, Registers: []Services.QueueState{{Id: 0, Serving: 1, Utilization: 0.25}}},
+	}
+	data := newReportData(samples)
+	data.standWaits[Services.Gas] = []int{1, 2, 3}
+	data.registerWaits = []int{0, 1}
+	path := filepath.Join(t.TempDir(), "report.html")
+	if err := writeReport(path, config, testResults(), data); err != nil {
+		t.Fatal(err)
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	page := string(content)
+	for _, want := range []string{"Stand 0 (gas) queue length", "gas waiting time (ms)", "Register waiting time (ms)",
+		"Register 0: 25.0", "<td>Gas</td><td>10</td>", "Warm-up (cars): first 2 cars", "serve_time_min"} {
+		if !strings.Contains(page, want) {
+			t.Errorf("report lacks %q", want)
+		}
+	}
+	if strings.Contains(page, "<script") || strings.Contains(page, "<link") {
+		t.Error("report loads external resources")
+	}
+}
@@ -0,0 +1,397 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"goenv/Services"
+	"gopkg.in/yaml.v2"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Variables
+
+// Server limits
+
+var maxConfigSize int64 = 1 << 20
+var maxQueuedRuns = 100
+
+// Progress streaming
+
+var defaultStreamInterval = 100 * time.Millisecond
+var minStreamInterval = 10 * time.Millisecond
+
+// Initializations
+
+// Constants for run states
+const (
+	statusQueued  = "queued"
+	statusRunning = "running"
+	statusDone    = "done"
+	statusFailed  = "failed"
+)
+
+// run is a simulation submitted through the api
+type run struct {
+	id        int
+	status    string
+	err       string
+	submitted time.Time
+	started   time.Time
+	finished  time.Time
+	config    Config
+	station   *Services.Station
+	results   *Results
+}
+
+// RunProgress is a struct for the progress of a run in api responses
+type RunProgress struct {
+	CarsTotal   int `json:"cars_total"`
+	CarsArrived int `json:"cars_arrived"`
+	CarsServed  int `json:"cars_served"`
+}
+
+// RunInfo is a struct for a run in api responses
+type RunInfo struct {
+	ID        int         `json:"id"`
+	Status    string      `json:"status"`
+	Error     string      `json:"error,omitempty"`
+	Submitted time.Time   `json:"submitted"`
+	Started   *time.Time  `json:"started,omitempty"`
+	Finished  *time.Time  `json:"finished,omitempty"`
+	Progress  RunProgress `json:"progress"`
+}
+
+// runServer keeps submitted runs and executes them on a bounded worker pool
+type runServer struct {
+	mu      sync.Mutex
+	runs    map[int]*run
+	nextID  int
+	jobs    chan *run
+	metrics *metricsRegistry
+}
+
+// newRunServer creates a run server with the given number of workers
+func newRunServer(workers int) *runServer {
+	s := &runServer{
+		runs:    make(map[int]*run),
+		jobs:    make(chan *run, maxQueuedRuns),
+		metrics: newMetricsRegistry(),
+	}
+	for i := 0; i < workers; i++ {
+		go s.workerRoutine()
+	}
+	return s
+}
+
+// handler returns the http routes of the api
+func (s *runServer) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /runs", s.handleSubmit)
+	mux.HandleFunc("GET /runs", s.handleList)
+	mux.HandleFunc("GET /runs/{id}", s.handleStatus)
+	mux.HandleFunc("GET /runs/{id}/results", s.handleResults)
+	mux.HandleFunc("GET /runs/{id}/stream", s.handleStream)
+	mux.HandleFunc("DELETE /runs/{id}", s.handleDelete)
+	mux.Handle("GET /metrics", s.metrics)
+	return mux
+}
+
+// Routines
+
+// serve runs the http api until the server fails
+func serve(addr string, workers int) error {
+	s := newRunServer(workers)
+	log.Printf("Serving simulation api on %s with %d workers", addr, workers)
+	return http.ListenAndServe(addr, s.handler())
+}
+
+// workerRoutine executes queued runs one after another
+func (s *runServer) workerRoutine() {
+	for r := range s.jobs {
+		s.execute(r)
+	}
+}
+
+// execute runs a queued run, a panicking simulation fails the run instead of the worker
+func (s *runServer) execute(r *run) {
+	s.mu.Lock()
+	// Skipping runs deleted while queued
+	if s.runs[r.id] != r {
+		s.mu.Unlock()
+		return
+	}
+	station, err := r.config.newStation()
+	r.started = time.Now()
+	if err != nil {
+		r.status = statusFailed
+		r.err = err.Error()
+		r.finished = r.started
+		s.mu.Unlock()
+		return
+	}
+	// Running from here on so a delete can no longer drop the run and leak its metrics
+	r.status = statusRunning
+	s.mu.Unlock()
+
+	defer func() {
+		if p := recover(); p != nil {
+			log.Printf("Run %d panicked: %v", r.id, p)
+			s.mu.Lock()
+			r.status = statusFailed
+			r.err = fmt.Sprintf("simulation panicked: %v", p)
+			r.finished = time.Now()
+			s.mu.Unlock()
+		}
+	}()
+
+	// Station is published only once open so snapshots never race its setup
+	station.Log = io.Discard
+	station.Open()
+	s.mu.Lock()
+	r.station = station
+	s.mu.Unlock()
+	metrics := newRunMetrics(station, "run", strconv.Itoa(r.id))
+	s.metrics.add(strconv.Itoa(r.id), metrics)
+	stats := aggregate(station, metrics.collectCars(station.Exit), r.config)
+	results := Results{Metadata: newMetadata(r.config, station.Seed, r.started, time.Now()), FinalStats: stats}
+
+	s.mu.Lock()
+	r.status = statusDone
+	r.results = &results
+	r.finished = results.Metadata.End
+	s.mu.Unlock()
+}
+
+// Handlers
+
+// handleSubmit queues a run of a yaml or json config posted over the defaults
+func (s *runServer) handleSubmit(w http.ResponseWriter, req *http.Request) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, req.Body, maxConfigSize))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "error reading config: %v", err)
+		return
+	}
+	// Json is a subset of yaml so both are read the same way
+	config := defaultConfig()
+	if err = yaml.Unmarshal(body, &config); err != nil {
+		writeError(w, http.StatusBadRequest, "error unmarshalling config: %v", err)
+		return
+	}
+	if config.Cars.Trace.File != "" || config.Events.File != "" || config.TimeSeries.File != "" {
+		writeError(w, http.StatusBadRequest, "trace, event log and time series files are not supported over the api")
+		return
+	}
+	if err = config.validate(); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid config: %v", err)
+		return
+	}
+
+	// Workers read the id only under the lock, so it is given once the run is queued
+	s.mu.Lock()
+	r := &run{status: statusQueued, submitted: time.Now(), config: config}
+	select {
+	case s.jobs <- r:
+		s.nextID++
+		r.id = s.nextID
+		s.runs[r.id] = r
+	default:
+		s.mu.Unlock()
+		writeError(w, http.StatusServiceUnavailable, "too many queued runs")
+		return
+	}
+	info := r.info()
+	s.mu.Unlock()
+
+	w.Header().Set("Location", fmt.Sprintf("/runs/%d", r.id))
+	writeJSON(w, http.StatusAccepted, info)
+}
+
+// handleList lists all known runs
+func (s *runServer) handleList(w http.ResponseWriter, req *http.Request) {
+	s.mu.Lock()
+	infos := make([]RunInfo, 0, len(s.runs))
+	for _, r := range s.runs {
+		infos = append(infos, r.info())
+	}
+	s.mu.Unlock()
+	sort.Slice(infos, func(i, j int) bool { return infos[i].ID < infos[j].ID })
+	writeJSON(w, http.StatusOK, infos)
+}
+
+// handleStatus returns the status and progress of a run
+func (s *runServer) handleStatus(w http.ResponseWriter, req *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r := s.lookup(w, req)
+	if r == nil {
+		return
+	}
+	writeJSON(w, http.StatusOK, r.info())
+}
+
+// handleResults returns the results of a finished run in any of the output formats
+func (s *runServer) handleResults(w http.ResponseWriter, req *http.Request) {
+	s.mu.Lock()
+	r := s.lookup(w, req)
+	if r == nil {
+		s.mu.Unlock()
+		return
+	}
+	results, status := r.results, r.status
+	s.mu.Unlock()
+	if results == nil {
+		writeError(w, http.StatusConflict, "run %d is %s", r.id, status)
+		return
+	}
+	name := req.URL.Query().Get("format")
+	if name == "" {
+		name = "json"
+	}
+	format, err := findFormat(name)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "%v", err)
+		return
+	}
+	encoded, err := format.encode(*results)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "error encoding results as %s: %v", format.name, err)
+		return
+	}
+	w.Header().Set("Content-Type", format.contentType)
+	w.Write(encoded)
+}
+
+// handleStream streams station snapshots of a run as server-sent events until it finishes
+func (s *runServer) handleStream(w http.ResponseWriter, req *http.Request) {
+	interval := defaultStreamInterval
+	if value := req.URL.Query().Get("interval"); value != "" {
+		parsed, err := time.ParseDuration(value)
+		if err != nil || parsed < minStreamInterval {
+			writeError(w, http.StatusBadRequest, "interval must be a duration of at least %v", minStreamInterval)
+			return
+		}
+		interval = parsed
+	}
+	s.mu.Lock()
+	r := s.lookup(w, req)
+	s.mu.Unlock()
+	if r == nil {
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "streaming is not supported")
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		s.mu.Lock()
+		station, status, info := r.station, r.status, r.info()
+		s.mu.Unlock()
+		if status == statusDone || status == statusFailed {
+			writeEvent(w, "end", info)
+			flusher.Flush()
+			return
+		}
+		if station != nil {
+			writeEvent(w, "progress", station.Snapshot())
+			flusher.Flush()
+		}
+		select {
+		case <-req.Context().Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// handleDelete removes a queued or finished run
+func (s *runServer) handleDelete(w http.ResponseWriter, req *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r := s.lookup(w, req)
+	if r == nil {
+		return
+	}
+	if r.status == statusRunning {
+		writeError(w, http.StatusConflict, "run %d is still running", r.id)
+		return
+	}
+	delete(s.runs, r.id)
+	s.metrics.remove(strconv.Itoa(r.id))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Utilities
+
+// lookup finds the run addressed by the request or writes a not found error
+//
+// Must be called with the server lock held.
+func (s *runServer) lookup(w http.ResponseWriter, req *http.Request) *run {
+	id, err := strconv.Atoi(req.PathValue("id"))
+	if err != nil || s.runs[id] == nil {
+		writeError(w, http.StatusNotFound, "run %s not found", req.PathValue("id"))
+		return nil
+	}
+	return s.runs[id]
+}
+
+// info describes the run for api responses, must be called with the server lock held
+func (r *run) info() RunInfo {
+	info := RunInfo{
+		ID:        r.id,
+		Status:    r.status,
+		Error:     r.err,
+		Submitted: r.submitted,
+		Progress:  RunProgress{CarsTotal: r.config.Cars.Count},
+	}
+	if !r.started.IsZero() {
+		info.Started = &r.started
+	}
+	if !r.finished.IsZero() {
+		info.Finished = &r.finished
+	}
+	if r.station != nil {
+		info.Progress = RunProgress{
+			CarsTotal:   r.station.TotalCars(),
+			CarsArrived: int(r.station.Arrived.Load()),
+			CarsServed:  int(r.station.Served.Load()),
+		}
+	}
+	return info
+}
+
+// writeJSON writes a json response with the given status code
+func writeJSON(w http.ResponseWriter, code int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(value); err != nil {
+		log.Printf("Error writing response: %v", err)
+	}
+}
+
+// writeEvent writes a single server-sent event with a json payload
+func writeEvent(w io.Writer, name string, value interface{}) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		log.Printf("Error marshalling event: %v", err)
+		return
+	}
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", name, data)
+}
+
+// writeError writes a json error response
+func writeError(w http.ResponseWriter, code int, format string, args ...interface{}) {
+	writeJSON(w, code, map[string]string{"error": fmt.Sprintf(format, args...)})
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"goenv/Services"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// request sends a request without body to the api
+func request(handler http.Handler, method, path string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(method, path, nil))
+	return rec
+}
+
+// waitRun polls the status of a run until it has finished
+func waitRun(t *testing.T, handler http.Handler, path string) RunInfo {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		var info RunInfo
+		rec := request(handler, "GET", path)
+		if err := json.Unmarshal(rec.Body.Bytes(), &info); err != nil {
+			t.Fatalf("decode status %s: %v", rec.Body, err)
+		}
+		if info.Status == statusDone || info.Status == statusFailed {
+			return info
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("run %s is still %s", path, info.Status)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// submit posts a config to the api and returns the response
+func submit(t *testing.T, handler http.Handler, config string) *httptest.ResponseRecorder {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("POST", "/runs", strings.NewReader(config)))
+	return rec
+}
+
+func TestSubmitQueueFullKeepsRunIds(t *testing.T) {
+	defer func(queued int) { maxQueuedRuns = queued }(maxQueuedRuns)
+	maxQueuedRuns = 1
+	// Without workers the queue stays full until drained here
+	s := newRunServer(0)
+	handler := s.handler()
+	ids := func(rec *httptest.ResponseRecorder) int {
+		var info RunInfo
+		if err := json.Unmarshal(rec.Body.Bytes(), &info); err != nil {
+			t.Fatalf("decode %s: %v", rec.Body, err)
+		}
+		return info.ID
+	}
+	if rec := submit(t, handler, "cars: {count: 5}"); rec.Code != http.StatusAccepted || ids(rec) != 1 {
+		t.Fatalf("first run = %d %s, want accepted run 1", rec.Code, rec.Body)
+	}
+	for i := 0; i < 3; i++ {
+		if rec := submit(t, handler, "cars: {count: 5}"); rec.Code != http.StatusServiceUnavailable {
+			t.Fatalf("run on a full queue = %d, want 503", rec.Code)
+		}
+	}
+	<-s.jobs
+	rec := submit(t, handler, "cars: {count: 5}")
+	if rec.Code != http.StatusAccepted || ids(rec) != 2 || rec.Header().Get("Location") != "/runs/2" {
+		t.Errorf("run after rejections = %d %s at %q, want accepted run 2", rec.Code, rec.Body, rec.Header().Get("Location"))
+	}
+}
+
+func TestRunLifecycle(t *testing.T) {
+	handler := newRunServer(1).handler()
+	rec := submit(t, handler, `{"seed": 7, "cars": {"count": 10}}`)
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("submit = %d %s, want 202", rec.Code, rec.Body)
+	}
+	location := rec.Header().Get("Location")
+	info := waitRun(t, handler, location)
+	if info.Status != statusDone || info.Progress.CarsServed != 10 || info.Finished == nil {
+		t.Fatalf("finished run = %+v, want done with 10 served cars", info)
+	}
+
+	var list []RunInfo
+	if rec := request(handler, "GET", "/runs"); rec.Code != http.StatusOK || json.Unmarshal(rec.Body.Bytes(), &list) != nil || len(list) != 1 {
+		t.Errorf("list = %d %s, want the one run", rec.Code, rec.Body)
+	}
+
+	var results Results
+	rec = request(handler, "GET", location+"/results")
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "application/json" {
+		t.Fatalf("json results = %d %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &results); err != nil {
+		t.Fatalf("decode results: %v", err)
+	}
+	cars := 0
+	for _, group := range results.groups()[:4] {
+		cars += group.Stats.TotalCars
+	}
+	if cars != 10 || results.Metadata.Seed != 7 {
+		t.Errorf("results count %d cars with seed %d, want 10 cars with seed 7", cars, results.Metadata.Seed)
+	}
+	for _, format := range outputFormats {
+		rec := request(handler, "GET", location+"/results?format="+format.name)
+		if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != format.contentType || rec.Body.Len() == 0 {
+			t.Errorf("%s results = %d %q", format.name, rec.Code, rec.Header().Get("Content-Type"))
+		}
+	}
+	if rec := request(handler, "GET", location+"/results?format=xml"); rec.Code != http.StatusBadRequest {
+		t.Errorf("xml results = %d, want 400", rec.Code)
+	}
+
+	if rec := request(handler, "DELETE", location); rec.Code != http.StatusNoContent {
+		t.Errorf("delete = %d, want 204", rec.Code)
+	}
+	if rec := request(handler, "GET", location); rec.Code != http.StatusNotFound {
+		t.Errorf("status of a deleted run = %d, want 404", rec.Code)
+	}
+}
+
+func TestRunRequestErrors(t *testing.T) {
+	// Without workers submitted runs stay queued
+	handler := newRunServer(0).handler()
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		want   int
+	}{
+		{"invalid yaml", "POST", "/runs", "cars: [", http.StatusBadRequest},
+		{"invalid config", "POST", "/runs", "cars: {count: -1}", http.StatusBadRequest},
+		{"trace file", "POST", "/runs", "cars: {trace: {file: trace.csv}}", http.StatusBadRequest},
+		{"unknown run", "GET", "/runs/42", "", http.StatusNotFound},
+		{"invalid id", "GET", "/runs/abc/results", "", http.StatusNotFound},
+		{"stream interval", "GET", "/runs/1/stream?interval=1ms", "", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body)))
+		if rec.Code != tt.want {
+			t.Errorf("%s: got %d %s, want %d", tt.name, rec.Code, rec.Body, tt.want)
+		}
+		var body map[string]string
+		if json.Unmarshal(rec.Body.Bytes(), &body) != nil || body["error"] == "" {
+			t.Errorf("%s: body %s has no error message", tt.name, rec.Body)
+		}
+	}
+	if rec := submit(t, handler, "cars: {count: 5}"); rec.Code != http.StatusAccepted {
+		t.Fatalf("submit = %d, want 202", rec.Code)
+	}
+	if rec := request(handler, "GET", "/runs/1/results"); rec.Code != http.StatusConflict {
+		t.Errorf("results of a queued run = %d, want 409", rec.Code)
+	}
+}
+
+func TestRunStream(t *testing.T) {
+	server := httptest.NewServer(newRunServer(1).handler())
+	defer server.Close()
+	resp, err := http.Post(server.URL+"/runs", "application/yaml", strings.NewReader("seed: 3\ncars: {count: 300}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	stream, err := http.Get(server.URL + resp.Header.Get("Location") + "/stream?interval=10ms")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stream.Body.Close()
+	if stream.StatusCode != http.StatusOK || stream.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("stream = %d %q, want an event stream", stream.StatusCode, stream.Header.Get("Content-Type"))
+	}
+
+	// Reading events until the run ends
+	var progress []Services.Snapshot
+	var end *RunInfo
+	name := ""
+	scanner := bufio.NewScanner(stream.Body)
+	scanner.Buffer(nil, 1<<20)
+	for end == nil && scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "event: "):
+			name = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: ") && name == "progress":
+			var snapshot Services.Snapshot
+			if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &snapshot); err != nil {
+				t.Fatalf("decode progress: %v", err)
+			}
+			progress = append(progress, snapshot)
+		case strings.HasPrefix(line, "data: ") && name == "end":
+			end = &RunInfo{}
+			if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), end); err != nil {
+				t.Fatalf("decode end: %v", err)
+			}
+		}
+	}
+	if end == nil {
+		t.Fatalf("stream closed without an end event: %v", scanner.Err())
+	}
+	if end.Status != statusDone || end.Progress.CarsServed != 300 {
+		t.Errorf("end = %+v, want done with 300 served cars", end)
+	}
+	if len(progress) == 0 {
+		t.Fatal("no progress events before the end")
+	}
+	for i, snapshot := range progress {
+		if len(snapshot.Stands) != 6 || len(snapshot.Registers) != 2 {
+			t.Errorf("progress %d has %d stands and %d registers, want 6 and 2", i, len(snapshot.Stands), len(snapshot.Registers))
+		}
+		if i > 0 && (snapshot.CarsArrived < progress[i-1].CarsArrived || snapshot.CarsServed < progress[i-1].CarsServed) {
+			t.Errorf("progress %d counts %d arrived and %d served, fewer than before", i, snapshot.CarsArrived, snapshot.CarsServed)
+		}
+	}
+}
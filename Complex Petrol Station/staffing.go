@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+	"goenv/Services"
+)
+
+// Initializations
+
+// WindowConfig is a struct for a period of simulated ms, open ended when to is zero
+type WindowConfig struct {
+	From int `yaml:"from" json:"from"`
+	To   int `yaml:"to" json:"to"`
+}
+
+// RegisterStaffingConfig is a struct for the staffing of a single register
+type RegisterStaffingConfig struct {
+	Id       int            `yaml:"id" json:"id"`
+	Windows  []WindowConfig `yaml:"windows" json:"windows"` // staffed for the whole run when empty
+	Breaks   []WindowConfig `yaml:"breaks" json:"breaks"`
+	OnDemand struct {
+		OpenAbove int `yaml:"open_above" json:"open_above"` // cars waiting to pay above which the register opens
+		CloseAt   int `yaml:"close_at" json:"close_at"`     // cars waiting to pay at or below which it closes
+	} `yaml:"on_demand" json:"on_demand"`
+}
+
+// StaffingConfig is a struct for the configuration of cashier shifts
+type StaffingConfig struct {
+	Registers []RegisterStaffingConfig `yaml:"registers" json:"registers"`
+}
+
+// RegisterStaffingStats is a struct for output construction of the staffing of a register
+type RegisterStaffingStats struct {
+	Id       int `yaml:"id" json:"id"`
+	Staffed  int `yaml:"staffed" json:"staffed"` // ms
+	Openings int `yaml:"openings" json:"openings"`
+}
+
+// StaffingStats is a struct for output construction of cashier work during a run
+type StaffingStats struct {
+	StaffHours float64                 `yaml:"staff_hours" json:"staff_hours"`
+	Registers  []RegisterStaffingStats `yaml:"registers" json:"registers"`
+}
+
+// Utilities
+
+// staffing converts the config to station staffing, nil when every register is always staffed
+func (staffing StaffingConfig) staffing() map[int]Services.Staffing {
+	if len(staffing.Registers) == 0 {
+		return nil
+	}
+	result := make(map[int]Services.Staffing)
+	for _, register := range staffing.Registers {
+		s := Services.Staffing{OnDemandAbove: register.OnDemand.OpenAbove, OnDemandClose: register.OnDemand.CloseAt}
+		for _, w := range register.Windows {
+			s.Windows = append(s.Windows, Services.Window{From: int64(w.From), To: int64(w.To)})
+		}
+		for _, b := range register.Breaks {
+			s.Breaks = append(s.Breaks, Services.Window{From: int64(b.From), To: int64(b.To)})
+		}
+		result[register.Id] = s
+	}
+	return result
+}
+
+// validate checks the shifts and that some register stays staffed once all shifts are over
+func (staffing StaffingConfig) validate(registers int) error {
+	seen := make(map[int]bool)
+	staffedAtEnd := registers - len(staffing.Registers)
+	for _, register := range staffing.Registers {
+		if register.Id < 0 || register.Id >= registers || seen[register.Id] {
+			return fmt.Errorf("staffing.registers ids must be unique and below registers.count")
+		}
+		seen[register.Id] = true
+		for _, w := range register.Windows {
+			if w.From < 0 || (w.To != 0 && w.To <= w.From) {
+				return fmt.Errorf("staffing windows of register %d need 0 <= from < to, or to 0 for no end", register.Id)
+			}
+		}
+		for _, b := range register.Breaks {
+			if b.From < 0 || b.To <= b.From {
+				return fmt.Errorf("staffing breaks of register %d need 0 <= from < to", register.Id)
+			}
+		}
+		onDemand := register.OnDemand
+		if onDemand.OpenAbove < 0 || onDemand.CloseAt < 0 || (onDemand.OpenAbove > 0 && onDemand.CloseAt >= onDemand.OpenAbove) {
+			return fmt.Errorf("staffing on_demand of register %d needs 0 <= close_at < open_above", register.Id)
+		}
+		if onDemand.OpenAbove > 0 {
+			continue
+		}
+		for _, w := range register.Windows {
+			if w.To == 0 {
+				staffedAtEnd++
+				break
+			}
+		}
+		if len(register.Windows) == 0 {
+			staffedAtEnd++
+		}
+	}
+	// Cars would wait forever once no cashier is left
+	if registers > 0 && staffedAtEnd == 0 {
+		return fmt.Errorf("staffing must keep a register that is not on demand staffed after the last shift")
+	}
+	return nil
+}
+
+// staffingStats summarizes how long the registers were staffed
+func staffingStats(staffed []Services.RegisterStaffing, hour int) *StaffingStats {
+	stats := &StaffingStats{}
+	for _, register := range staffed {
+		stats.StaffHours += float64(register.Staffed) / float64(hour)
+		stats.Registers = append(stats.Registers, RegisterStaffingStats{
+			Id:       register.Id,
+			Staffed:  int(register.Staffed),
+			Openings: register.Openings,
+		})
+	}
+	return stats
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"goenv/Services"
+	"strings"
+	"testing"
+)
+
+func TestStaffingValidate(t *testing.T) {
+	register := func(id int, windows ...WindowConfig) RegisterStaffingConfig {
+		return RegisterStaffingConfig{Id: id, Windows: windows}
+	}
+	onDemand := func(id, openAbove, closeAt int) RegisterStaffingConfig {
+		r := register(id)
+		r.OnDemand.OpenAbove, r.OnDemand.CloseAt = openAbove, closeAt
+		return r
+	}
+	withBreak := register(0)
+	withBreak.Breaks = []WindowConfig{{From: 50, To: 50}}
+	tests := []struct {
+		name      string
+		registers int
+		staffing  []RegisterStaffingConfig
+		invalid   string
+	}{
+		{"none", 2, nil, ""},
+		{"shift and open end", 2, []RegisterStaffingConfig{register(0, WindowConfig{0, 100}), register(1, WindowConfig{50, 0})}, ""},
+		{"unlisted register stays", 2, []RegisterStaffingConfig{register(0, WindowConfig{0, 100})}, ""},
+		{"every shift ends", 1, []RegisterStaffingConfig{register(0, WindowConfig{0, 100})}, "after the last shift"},
+		{"only on demand", 1, []RegisterStaffingConfig{onDemand(0, 3, 1)}, "after the last shift"},
+		{"on demand beside staffed", 2, []RegisterStaffingConfig{onDemand(1, 3, 1)}, ""},
+		{"id out of range", 2, []RegisterStaffingConfig{register(2)}, "ids must be unique"},
+		{"duplicate id", 2, []RegisterStaffingConfig{register(0), register(0)}, "ids must be unique"},
+		{"reversed window", 2, []RegisterStaffingConfig{register(0, WindowConfig{100, 50})}, "windows of register 0"},
+		{"empty break", 2, []RegisterStaffingConfig{withBreak}, "breaks of register 0"},
+		{"close above open", 2, []RegisterStaffingConfig{onDemand(1, 2, 2)}, "on_demand of register 1"},
+	}
+	for _, tt := range tests {
+		err := StaffingConfig{Registers: tt.staffing}.validate(tt.registers)
+		if (err != nil) != (tt.invalid != "") || err != nil && !strings.Contains(err.Error(), tt.invalid) {
+			t.Errorf("%s: validate = %v, want error about %q", tt.name, err, tt.invalid)
+		}
+	}
+}
+
+func TestStaffingSetup(t *testing.T) {
+	if s := (StaffingConfig{}).staffing(); s != nil {
+		t.Errorf("staffing without registers = %v, want nil", s)
+	}
+	config := StaffingConfig{Registers: []RegisterStaffingConfig{{Id: 1,
+		Windows: []WindowConfig{{From: 0, To: 100}}, Breaks: []WindowConfig{{From: 40, To: 60}}}}}
+	config.Registers[0].OnDemand.OpenAbove, config.Registers[0].OnDemand.CloseAt = 3, 1
+	s, ok := config.staffing()[1]
+	if !ok || len(s.Windows) != 1 || s.Windows[0] != (Services.Window{From: 0, To: 100}) ||
+		len(s.Breaks) != 1 || s.Breaks[0] != (Services.Window{From: 40, To: 60}) || s.OnDemandAbove != 3 || s.OnDemandClose != 1 {
+		t.Errorf("staffing of register 1 = %+v", s)
+	}
+}
+
+func TestStaffingStats(t *testing.T) {
+	stats := staffingStats([]Services.RegisterStaffing{
+		{Id: 0, Staffed: 120, Openings: 1},
+		{Id: 2, Staffed: 30, Openings: 3},
+	}, 60)
+	if stats.StaffHours != 2.5 || len(stats.Registers) != 2 ||
+		stats.Registers[1] != (RegisterStaffingStats{Id: 2, Staffed: 30, Openings: 3}) {
+		t.Errorf("staffing stats = %+v, want 2.5 staff hours", stats)
+	}
+}
@@ -0,0 +1,385 @@
+package main
+
+import (
+	"goenv/Services"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Initializations
+
+// StationStats is a struct for output yaml construction
+type StationStats struct {
+	TotalCars    int `yaml:"total_cars" json:"total_cars"`
+	TotalTime    int `yaml:"total_time" json:"total_time"`
+	AvgQueueTime int `yaml:"avg_queue_time" json:"avg_queue_time"`
+	MaxQueueTime int `yaml:"max_queue_time" json:"max_queue_time"`
+	P95QueueTime int `yaml:"p95_queue_time" json:"p95_queue_time"`
+}
+
+// WarmupCutoff is a struct for output construction of the excluded warm-up period
+type WarmupCutoff struct {
+	Method string `yaml:"method" json:"method"` // cars, time or mser5
+	Cars   int    `yaml:"cars" json:"cars"`     // number of excluded cars
+	Time   int    `yaml:"time" json:"time"`     // arrival time of the first counted car
+}
+
+// FinalStats is a struct for output yaml construction
+type FinalStats struct {
+	Gas       StationStats   `yaml:"Gas" json:"Gas"`
+	Diesel    StationStats   `yaml:"Diesel" json:"Diesel"`
+	LPG       StationStats   `yaml:"LPG" json:"LPG"`
+	Electric  StationStats   `yaml:"Electric" json:"Electric"`
+	Registers StationStats   `yaml:"Registers" json:"Registers"`
+	Warmup    *WarmupCutoff  `yaml:"Warmup,omitempty" json:"Warmup,omitempty"`
+	Economics *Economics     `yaml:"Economics,omitempty" json:"Economics,omitempty"`
+	Staffing  *StaffingStats `yaml:"Staffing,omitempty" json:"Staffing,omitempty"`
+	Wash      *WashStats     `yaml:"Wash,omitempty" json:"Wash,omitempty"`
+	Stands    []StandStats   `yaml:"Stands,omitempty" json:"Stands,omitempty"`
+	Layout    *LayoutStats   `yaml:"Layout,omitempty" json:"Layout,omitempty"`
+	Classes   []ClassStats   `yaml:"Classes,omitempty" json:"Classes,omitempty"`
+	Payments  []PaymentStats `yaml:"Payments,omitempty" json:"Payments,omitempty"`
+	// Registers by class, only with self-service registers
+	RegisterClasses map[string]StationStats `yaml:"RegisterClasses,omitempty" json:"RegisterClasses,omitempty"`
+}
+
+// Routines
+
+// aggregate collects the cars leaving the station and computes statistics past the warm-up
+func aggregate(station *Services.Station, exit <-chan *Services.Car, config Config) FinalStats {
+	var cars []*Services.Car
+	// Exit queue collects cars
+	for car := range exit {
+		car.TotalTime = time.Duration(time.Since(car.StandQueueEnter).Milliseconds())
+		cars = append(cars, car)
+	}
+	sortByArrival(cars)
+	cutoff := config.Warmup.cutoff(cars)
+	stats := computeStats(cars[cutoff.Cars:])
+	if cutoff.Method != "" {
+		stats.Warmup = &cutoff
+	}
+	if config.selfService() {
+		stats.RegisterClasses = make(map[string]StationStats)
+		for _, class := range registerClassOrder {
+			if class != Services.Staffed && config.registerClasses()[class].Count == 0 {
+				continue
+			}
+			var classCars []*Services.Car
+			for _, car := range cars[cutoff.Cars:] {
+				if car.RegisterClass == class {
+					classCars = append(classCars, car)
+				}
+			}
+			stats.RegisterClasses[className(class)] = registerStats(classCars)
+		}
+	}
+	if config.Wash.enabled() {
+		stats.Wash = washStats(cars[cutoff.Cars:], station.Snapshot().WashBays)
+	}
+	if config.multiProduct() {
+		stats.Stands = standStats(cars[cutoff.Cars:], station.Stands)
+	}
+	if len(station.Lanes) > 0 {
+		stats.Layout = layoutStats(cars[cutoff.Cars:], station.Lanes)
+	}
+	if config.Classes.enabled() {
+		stats.Classes = config.Classes.classStats(cars[cutoff.Cars:])
+	}
+	if config.Payments.enabled() {
+		stats.Payments = config.Payments.paymentStats(cars[cutoff.Cars:])
+	}
+	staffing := staffingStats(station.StaffedTime(), config.Economics.Hour)
+	if len(config.Staffing.Registers) > 0 {
+		stats.Staffing = staffing
+	}
+	if config.Economics.enabled() {
+		balked := make(map[Services.FuelType]int)
+		for _, fuel := range station.Snapshot().Fuels {
+			balked[fuel.Fuel] = fuel.Balked
+		}
+		stats.Economics = config.Economics.evaluate(cars, config, balked, staffing.StaffHours)
+	}
+	return stats
+}
+
+// computeStats computes global data about the station from the counted cars
+func computeStats(cars []*Services.Car) FinalStats {
+	var totalCars int
+	var totalRegisterTime time.Duration
+	var totalRegisterQueue time.Duration
+	maxRegisterQueue := 0
+	// Gas
+	var totalGasTime time.Duration
+	var totalGasQueue time.Duration
+	maxGasQueue := 0
+	gasCount := 0
+	// Diesel
+	var totalDieselTime time.Duration
+	var totalDieselQueue time.Duration
+	maxDieselQueue := 0
+	dieselCount := 0
+	// LPG
+	var totalLPGTime time.Duration
+	var totalLPGQueue time.Duration
+	maxLPGQueue := 0
+	lpgCount := 0
+	// Electric
+	var totalElectricTime time.Duration
+	var totalElectricQueue time.Duration
+	maxElectricQueue := 0
+	electricCount := 0
+	// Counted cars aggregate data
+	for _, car := range cars {
+		totalCars++
+		totalRegisterTime += car.PayTime
+		totalRegisterQueue += car.RegisterQueueTime
+		if int(car.RegisterQueueTime) > maxRegisterQueue {
+			maxRegisterQueue = int(car.RegisterQueueTime)
+		}
+		switch car.Fuel {
+		case Services.Gas:
+			//totalGasTime += car.TotalTime
+			totalGasTime += car.FuelTime
+			totalGasQueue += car.StandQueueTime
+			gasCount++
+			if int(car.StandQueueTime) > maxGasQueue {
+				maxGasQueue = int(car.StandQueueTime)
+			}
+		case Services.Diesel:
+			//totalDieselTime += car.TotalTime
+			totalDieselTime += car.FuelTime
+			totalDieselQueue += car.StandQueueTime
+			dieselCount++
+			if int(car.StandQueueTime) > maxDieselQueue {
+				maxDieselQueue = int(car.StandQueueTime)
+			}
+		case Services.LPG:
+			//totalLPGTime += car.TotalTime
+			totalLPGTime += car.FuelTime
+			totalLPGQueue += car.StandQueueTime
+			lpgCount++
+			if int(car.StandQueueTime) > maxLPGQueue {
+				maxLPGQueue = int(car.StandQueueTime)
+			}
+		case Services.Electric:
+			//totalElectricTime += car.TotalTime
+			totalElectricTime += car.FuelTime
+			totalElectricQueue += car.StandQueueTime
+			electricCount++
+			if int(car.StandQueueTime) > maxElectricQueue {
+				maxElectricQueue = int(car.StandQueueTime)
+			}
+		}
+		//fmt.Printf("Car %s: \n Queue: %d \n Fuel: %d \n Pay: %d \n", car.Fuel, car.StandQueueTime, car.FuelTime, car.PayTime)
+	}
+	// Calculating average values
+	var averageGasQueue int
+	if gasCount != 0 {
+		averageGasQueue = int(totalGasQueue) / gasCount
+	}
+	var averageDieselQueue int
+	if dieselCount != 0 {
+		averageDieselQueue = int(totalDieselQueue) / dieselCount
+	}
+	var averageLPGQueue int
+	if lpgCount != 0 {
+		averageLPGQueue = int(totalLPGQueue) / lpgCount
+	}
+	var averageElectricQueue int
+	if electricCount != 0 {
+		averageElectricQueue = int(totalElectricQueue) / electricCount
+	}
+	var averageRegisterQueue int
+	if totalCars != 0 {
+		averageRegisterQueue = int(totalRegisterQueue) / totalCars
+	}
+	// Creating final yaml
+	stats := FinalStats{
+		Gas: StationStats{
+			TotalCars:    gasCount,          // number of cars that went through this stand
+			TotalTime:    int(totalGasTime), // the total time cars spent fueling on the station for this stand
+			AvgQueueTime: averageGasQueue,   // average time spent in a queue for this stand
+			MaxQueueTime: maxGasQueue,       // max time spent in a queue for this stand
+		},
+		Diesel: StationStats{
+			TotalCars:    dieselCount,
+			TotalTime:    int(totalDieselTime),
+			AvgQueueTime: averageDieselQueue,
+			MaxQueueTime: maxDieselQueue,
+		},
+		LPG: StationStats{
+			TotalCars:    lpgCount,
+			TotalTime:    int(totalLPGTime),
+			AvgQueueTime: averageLPGQueue,
+			MaxQueueTime: maxLPGQueue,
+		},
+		Electric: StationStats{
+			TotalCars:    electricCount,
+			TotalTime:    int(totalElectricTime),
+			AvgQueueTime: averageElectricQueue,
+			MaxQueueTime: maxElectricQueue,
+		},
+		Registers: StationStats{
+			TotalCars:    totalCars,              // number of cars that went through payment
+			TotalTime:    int(totalRegisterTime), // total time spent at the register
+			AvgQueueTime: averageRegisterQueue,   // average time spent in the queues for the registers
+			MaxQueueTime: maxRegisterQueue,       // max time spent in the queues for the registers
+		},
+	}
+	// 95th percentiles of the queue times
+	stats.Gas.P95QueueTime = queuePercentile(cars, Services.Gas, 0.95)
+	stats.Diesel.P95QueueTime = queuePercentile(cars, Services.Diesel, 0.95)
+	stats.LPG.P95QueueTime = queuePercentile(cars, Services.LPG, 0.95)
+	stats.Electric.P95QueueTime = queuePercentile(cars, Services.Electric, 0.95)
+	stats.Registers.P95QueueTime = queuePercentile(cars, "", 0.95)
+	return stats
+}
+
+// registerClassOrder lists all register classes in output order
+var registerClassOrder = append([]Services.RegisterClass{Services.Staffed}, Services.SelfServiceClasses...)
+
+// className returns the output group name of a register class
+func className(class Services.RegisterClass) string {
+	return strings.ToUpper(string(class[:1])) + string(class[1:])
+}
+
+// registerStats computes payment statistics of the cars
+func registerStats(cars []*Services.Car) StationStats {
+	var stats StationStats
+	var totalQueue time.Duration
+	for _, car := range cars {
+		stats.TotalCars++
+		stats.TotalTime += int(car.PayTime)
+		totalQueue += car.RegisterQueueTime
+		stats.MaxQueueTime = max(stats.MaxQueueTime, int(car.RegisterQueueTime))
+	}
+	if stats.TotalCars != 0 {
+		stats.AvgQueueTime = int(totalQueue) / stats.TotalCars
+	}
+	stats.P95QueueTime = queuePercentile(cars, "", 0.95)
+	return stats
+}
+
+// queuePercentile returns a percentile of the stand queue times of a fuel, or of the register queue times without one
+func queuePercentile(cars []*Services.Car, fuel Services.FuelType, p float64) int {
+	var times []int
+	for _, car := range cars {
+		switch {
+		case fuel == "":
+			times = append(times, int(car.RegisterQueueTime))
+		case car.Fuel == fuel:
+			times = append(times, int(car.StandQueueTime))
+		}
+	}
+	return percentile(times, p)
+}
+
+// percentile returns a percentile of the times, zero without any
+func percentile(times []int, p float64) int {
+	if len(times) == 0 {
+		return 0
+	}
+	sort.Ints(times)
+	// Nearest rank method
+	rank := int(math.Ceil(p*float64(len(times)))) - 1
+	return times[max(rank, 0)]
+}
+
+// metric is a single named value of the final statistics
+type metric struct {
+	name  string
+	value float64
+}
+
+// metrics flattens the statistics into named values, e.g. gas.avg_queue_time
+func (stats FinalStats) metrics() []metric {
+	var metrics []metric
+	for _, group := range stats.groups() {
+		prefix := strings.ToLower(group.Name) + "."
+		metrics = append(metrics,
+			metric{prefix + "total_cars", float64(group.Stats.TotalCars)},
+			metric{prefix + "total_time", float64(group.Stats.TotalTime)},
+			metric{prefix + "avg_queue_time", float64(group.Stats.AvgQueueTime)},
+			metric{prefix + "max_queue_time", float64(group.Stats.MaxQueueTime)},
+			metric{prefix + "p95_queue_time", float64(group.Stats.P95QueueTime)},
+		)
+	}
+	if w := stats.Wash; w != nil {
+		metrics = append(metrics,
+			metric{"wash.total_cars", float64(w.TotalCars)},
+			metric{"wash.total_time", float64(w.TotalTime)},
+			metric{"wash.avg_queue_time", float64(w.AvgQueueTime)},
+			metric{"wash.max_queue_time", float64(w.MaxQueueTime)},
+			metric{"wash.p95_queue_time", float64(w.P95QueueTime)},
+			metric{"wash.utilization", w.Utilization},
+		)
+	}
+	if l := stats.Layout; l != nil {
+		metrics = append(metrics,
+			metric{"layout.blocked_cars", float64(l.BlockedCars)},
+			metric{"layout.avg_blocked_time", float64(l.AvgBlockedTime)},
+			metric{"layout.max_blocked_time", float64(l.MaxBlockedTime)},
+		)
+	}
+	for _, class := range stats.Classes {
+		prefix := "classes." + class.Class + "."
+		metrics = append(metrics,
+			metric{prefix + "total_cars", float64(class.TotalCars)},
+			metric{prefix + "avg_stand_queue_time", float64(class.AvgStandQueueTime)},
+			metric{prefix + "p95_stand_queue_time", float64(class.P95StandQueueTime)},
+			metric{prefix + "avg_register_queue_time", float64(class.AvgRegisterQueueTime)},
+			metric{prefix + "avg_total_time", float64(class.AvgTotalTime)},
+		)
+	}
+	for _, payment := range stats.Payments {
+		prefix := "payments." + payment.Method + "."
+		metrics = append(metrics,
+			metric{prefix + "total_cars", float64(payment.TotalCars)},
+			metric{prefix + "declined", float64(payment.Declined)},
+			metric{prefix + "avg_pay_time", float64(payment.AvgPayTime)},
+			metric{prefix + "avg_queue_time", float64(payment.AvgQueueTime)},
+		)
+	}
+	if e := stats.Economics; e != nil {
+		metrics = append(metrics,
+			metric{"economics.revenue", e.Revenue},
+			metric{"economics.gross_margin", e.GrossMargin},
+			metric{"economics.cost", e.Cost},
+			metric{"economics.profit", e.Profit},
+			metric{"economics.lost_revenue", e.LostRevenue},
+		)
+	}
+	return metrics
+}
+
+// metricIndex returns the position of the named metric or -1 when missing
+func metricIndex(metrics []metric, name string) int {
+	for i, m := range metrics {
+		if m.name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// higherIsBetter reports whether larger values of the metric are preferable, lower times and costs are better otherwise
+func higherIsBetter(name string) bool {
+	switch name {
+	case "economics.revenue", "economics.gross_margin", "economics.profit":
+		return true
+	}
+	return false
+}
+
+// isMetric reports whether the name is one of the flattened statistics
+func isMetric(name string) bool {
+	for _, m := range (FinalStats{Economics: &Economics{}, Layout: &LayoutStats{}, Wash: &WashStats{}, Classes: allClassStats(), Payments: allPaymentStats()}).metrics() {
+		if m.name == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"goenv/Services"
+	"testing"
+)
+
+func TestClassName(t *testing.T) {
+	tests := []struct {
+		class Services.RegisterClass
+		want  string
+	}{
+		{Services.Staffed, "Staffed"},
+		{Services.Kiosk, "Kiosk"},
+		{Services.Mobile, "Mobile"},
+	}
+	for _, tt := range tests {
+		if got := className(tt.class); got != tt.want {
+			t.Errorf("className(%s) = %s, want %s", tt.class, got, tt.want)
+		}
+	}
+}
+
+func TestRegisterStats(t *testing.T) {
+	tests := []struct {
+		name string
+		cars []*Services.Car
+		want StationStats
+	}{
+		{"no cars", nil, StationStats{}},
+		{
+			"paying cars",
+			[]*Services.Car{{PayTime: 3, RegisterQueueTime: 0}, {PayTime: 5, RegisterQueueTime: 4}, {PayTime: 4, RegisterQueueTime: 11}},
+			StationStats{TotalCars: 3, TotalTime: 12, AvgQueueTime: 5, MaxQueueTime: 11, P95QueueTime: 11},
+		},
+	}
+	for _, tt := range tests {
+		if got := registerStats(tt.cars); got != tt.want {
+			t.Errorf("%s: registerStats = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	tests := []struct {
+		times []int
+		p     float64
+		want  int
+	}{
+		{nil, 0.95, 0},
+		{[]int{7}, 0.95, 7},
+		{[]int{5, 1, 3, 2, 4}, 0.5, 3},
+		{[]int{5, 1, 3, 2, 4}, 0.95, 5},
+		{[]int{5, 1, 3, 2, 4}, 0, 1},
+		{[]int{10, 20, 30, 40, 50, 60, 70, 80, 90, 100}, 0.9, 90},
+		{[]int{10, 20, 30, 40, 50, 60, 70, 80, 90, 100}, 0.95, 100},
+	}
+	for _, tt := range tests {
+		if got := percentile(append([]int(nil), tt.times...), tt.p); got != tt.want {
+			t.Errorf("percentile(%v, %v) = %d, want %d", tt.times, tt.p, got, tt.want)
+		}
+	}
+}
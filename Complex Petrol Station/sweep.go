@@ -0,0 +1,232 @@
+package main
+
+import (
+	"fmt"
+	"gopkg.in/yaml.v2"
+	"io"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// Initializations
+
+// sweepDimension is a config value varied over a list of values
+type sweepDimension struct {
+	key    string
+	values []string
+}
+
+// sweepFile is a struct for sweep range files
+type sweepFile struct {
+	Parameters yaml.MapSlice `yaml:"parameters"`
+}
+
+// Commands
+
+// sweepCommand runs the cartesian product of config ranges and writes a table of key metrics
+func sweepCommand(args []string) error {
+	fs := newFlagSet("sweep")
+	var cf configFlags
+	cf.register(fs)
+	var vary overrides
+	fs.Var(&vary, "vary", "vary a config value, e.g. stations.gas.count=1..4 or registers.count=1,2,3 (repeatable)")
+	ranges := fs.String("ranges", "", "yaml file with a parameters map of config values to ranges")
+	parallel := fs.Int("parallel", runtime.NumCPU(), "number of simulations running at once")
+	out := fs.String("out", "sweep.csv", "path of the combined table, - for stdout")
+	format := fs.String("format", "csv", "table format: csv, markdown, json or yaml")
+	quiet := fs.Bool("quiet", false, "do not print progress")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	base, err := cf.load()
+	if err != nil {
+		return err
+	}
+	if _, err = (table{}).encode(*format); err != nil {
+		return configError{err}
+	}
+	if *parallel < 1 {
+		return configError{fmt.Errorf("parallel must be at least 1")}
+	}
+	// Collecting dimensions
+	var dimensions []sweepDimension
+	if *ranges != "" {
+		if dimensions, err = loadSweepFile(*ranges); err != nil {
+			return configError{err}
+		}
+	}
+	for _, assignment := range vary {
+		key, raw, found := strings.Cut(assignment, "=")
+		if !found {
+			return configError{fmt.Errorf("vary %q is not in key=values form", assignment)}
+		}
+		values, err := parseSweepValues(raw)
+		if err != nil {
+			return configError{err}
+		}
+		dimensions = append(dimensions, sweepDimension{key: key, values: values})
+	}
+	if len(dimensions) == 0 {
+		return configError{fmt.Errorf("nothing to sweep, use --vary or --ranges")}
+	}
+	for _, dimension := range dimensions {
+		if err = base.Layout.checkCountKey(dimension.key); err != nil {
+			return configError{err}
+		}
+	}
+	// Preparing all combinations before running any of them
+	var runs []*batchRun
+	combined := combinations(dimensions)
+	for _, values := range combined {
+		config := base
+		for i, dimension := range dimensions {
+			if err = config.set(dimension.key + "=" + values[i]); err != nil {
+				return configError{err}
+			}
+		}
+		if err = config.validate(); err != nil {
+			return configError{fmt.Errorf("invalid config for %s: %v", describeValues(dimensions, values), err)}
+		}
+		runs = append(runs, &batchRun{label: describeValues(dimensions, values), config: config})
+	}
+	progress := io.Writer(os.Stderr)
+	if *quiet {
+		progress = io.Discard
+	}
+	if err = runBatch(runs, *parallel, progress); err != nil {
+		return err
+	}
+
+	// Combined table
+	result := table{}
+	for _, dimension := range dimensions {
+		result.columns = append(result.columns, dimension.key)
+	}
+	result.columns = append(result.columns, "seed")
+	for _, m := range keyMetrics(FinalStats{}) {
+		result.columns = append(result.columns, m.name)
+	}
+	for i, run := range runs {
+		var row []interface{}
+		for _, value := range combined[i] {
+			row = append(row, value)
+		}
+		row = append(row, run.config.Seed)
+		for _, m := range keyMetrics(run.stats) {
+			row = append(row, m.value)
+		}
+		result.addRow(row...)
+	}
+	return writeTable(result, *format, *out)
+}
+
+// Utilities
+
+// loadSweepFile reads sweep dimensions from a yaml file in their listed order
+func loadSweepFile(path string) ([]sweepDimension, error) {
+	file, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading %s file: %v", path, err)
+	}
+	var sweep sweepFile
+	if err = yaml.UnmarshalStrict(file, &sweep); err != nil {
+		return nil, fmt.Errorf("error unmarshalling %s file: %v", path, err)
+	}
+	var dimensions []sweepDimension
+	for _, item := range sweep.Parameters {
+		key := fmt.Sprint(item.Key)
+		var raw []string
+		switch value := item.Value.(type) {
+		case []interface{}:
+			for _, element := range value {
+				raw = append(raw, fmt.Sprint(element))
+			}
+		default:
+			raw = append(raw, fmt.Sprint(value))
+		}
+		values, err := parseSweepValues(strings.Join(raw, ","))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", key, err)
+		}
+		dimensions = append(dimensions, sweepDimension{key: key, values: values})
+	}
+	return dimensions, nil
+}
+
+// parseSweepValues expands a comma separated list of values and integer ranges like 1..4
+func parseSweepValues(raw string) ([]string, error) {
+	var values []string
+	for _, part := range strings.Split(strings.Trim(raw, "[] "), ",") {
+		part = strings.TrimSpace(part)
+		if from, to, found := strings.Cut(part, ".."); found {
+			low, errLow := strconv.Atoi(strings.TrimSpace(from))
+			high, errHigh := strconv.Atoi(strings.TrimSpace(to))
+			if errLow != nil || errHigh != nil || high < low {
+				return nil, fmt.Errorf("invalid range %q", part)
+			}
+			for value := low; value <= high; value++ {
+				values = append(values, strconv.Itoa(value))
+			}
+		} else if part != "" {
+			values = append(values, part)
+		}
+	}
+	if len(values) == 0 {
+		return nil, fmt.Errorf("no values in %q", raw)
+	}
+	return values, nil
+}
+
+// combinations returns the cartesian product of the dimension values
+func combinations(dimensions []sweepDimension) [][]string {
+	result := [][]string{{}}
+	for _, dimension := range dimensions {
+		var next [][]string
+		for _, prefix := range result {
+			for _, value := range dimension.values {
+				combination := append(append([]string{}, prefix...), value)
+				next = append(next, combination)
+			}
+		}
+		result = next
+	}
+	return result
+}
+
+// describeValues formats a combination as key=value pairs
+func describeValues(dimensions []sweepDimension, values []string) string {
+	parts := make([]string, len(values))
+	for i, value := range values {
+		parts[i] = dimensions[i].key + "=" + value
+	}
+	return strings.Join(parts, " ")
+}
+
+// keyMetrics selects the queue times of every group and the number of served cars
+func keyMetrics(stats FinalStats) []metric {
+	var selected []metric
+	for _, m := range stats.metrics() {
+		if strings.HasSuffix(m.name, "queue_time") || m.name == "registers.total_cars" {
+			selected = append(selected, m)
+		}
+	}
+	return selected
+}
+
+// writeTable writes an encoded table to a file or stdout
+func writeTable(t table, format, out string) error {
+	encoded, err := t.encode(format)
+	if err != nil {
+		return err
+	}
+	if out == "-" {
+		_, err = os.Stdout.Write(encoded)
+		return err
+	}
+	if err = os.WriteFile(out, encoded, 0644); err != nil {
+		return fmt.Errorf("error writing %s: %v", out, err)
+	}
+	return nil
+}
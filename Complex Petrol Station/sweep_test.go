@@ -0,0 +1,114 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseSweepValues(t *testing.T) {
+	tests := []struct {
+		raw   string
+		want  []string
+		valid bool
+	}{
+		{"1,2,3", []string{"1", "2", "3"}, true},
+		{"1..4", []string{"1", "2", "3", "4"}, true},
+		{"[1, 3..4, 8]", []string{"1", "3", "4", "8"}, true},
+		{"uniform, trace", []string{"uniform", "trace"}, true},
+		{"2..2", []string{"2"}, true},
+		{"4..1", nil, false},
+		{"a..3", nil, false},
+		{" , ", nil, false},
+	}
+	for _, tt := range tests {
+		got, err := parseSweepValues(tt.raw)
+		if (err == nil) != tt.valid {
+			t.Errorf("parseSweepValues(%q) error = %v, want valid %v", tt.raw, err, tt.valid)
+			continue
+		}
+		if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("parseSweepValues(%q) = %v, want %v", tt.raw, got, tt.want)
+		}
+	}
+}
+
+func TestCombinations(t *testing.T) {
+	tests := []struct {
+		name       string
+		dimensions []sweepDimension
+		want       []string
+	}{
+		{"none", nil, []string{""}},
+		{"single", []sweepDimension{{"a", []string{"1", "2"}}}, []string{"1", "2"}},
+		{"product", []sweepDimension{{"a", []string{"1", "2"}}, {"b", []string{"x", "y", "z"}}},
+			[]string{"1 x", "1 y", "1 z", "2 x", "2 y", "2 z"}},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, values := range combinations(tt.dimensions) {
+			got = append(got, strings.Join(values, " "))
+		}
+		if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("%s: combinations = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestDescribeValues(t *testing.T) {
+	dimensions := []sweepDimension{{"stations.gas.count", nil}, {"registers.count", nil}}
+	if got, want := describeValues(dimensions, []string{"2", "3"}), "stations.gas.count=2 registers.count=3"; got != want {
+		t.Errorf("describeValues = %q, want %q", got, want)
+	}
+}
+
+func TestLoadSweepFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ranges.yaml")
+	content := "parameters:\n  registers.count: 1..2\n  stations.gas.count: [1, 3]\n  arrival.mode: poisson\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	dimensions, err := loadSweepFile(path)
+	if err != nil {
+		t.Fatalf("loadSweepFile: %v", err)
+	}
+	want := []string{"registers.count=1,2", "stations.gas.count=1,3", "arrival.mode=poisson"}
+	if len(dimensions) != len(want) {
+		t.Fatalf("loadSweepFile returned %d dimensions, want %d", len(dimensions), len(want))
+	}
+	for i, dimension := range dimensions {
+		if got := dimension.key + "=" + strings.Join(dimension.values, ","); got != want[i] {
+			t.Errorf("dimension %d = %q, want %q", i, got, want[i])
+		}
+	}
+}
+
+func TestTableEncode(t *testing.T) {
+	sample := table{columns: []string{"name", "value"}}
+	sample.addRow("a,b", 1.5)
+	sample.addRow("c", 2.0)
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{"csv", "name,value\n\"a,b\",1.50\nc,2\n"},
+		{"markdown", "| name | value |\n|---|---|\n| a,b | 1.50 |\n| c | 2 |\n"},
+		{"md", "| name | value |\n|---|---|\n| a,b | 1.50 |\n| c | 2 |\n"},
+		{"json", "[\n  {\"name\": \"a,b\", \"value\": 1.5},\n  {\"name\": \"c\", \"value\": 2}\n]\n"},
+		{"yaml", "- name: a,b\n  value: 1.5\n- name: c\n  value: 2\n"},
+	}
+	for _, tt := range tests {
+		got, err := sample.encode(tt.format)
+		if err != nil {
+			t.Errorf("%s: encode error %v", tt.format, err)
+			continue
+		}
+		if string(got) != tt.want {
+			t.Errorf("%s: encode = %q, want %q", tt.format, got, tt.want)
+		}
+	}
+	if _, err := sample.encode("xml"); err == nil {
+		t.Errorf("xml: encode error = nil, want unknown format")
+	}
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"gopkg.in/yaml.v2"
+	"math"
+	"strings"
+)
+
+// Initializations
+
+// table is a simple table of named columns used for combined outputs of several runs
+type table struct {
+	columns []string
+	rows    [][]interface{}
+}
+
+// addRow appends a row, cells are matched to columns by position
+func (t *table) addRow(cells ...interface{}) {
+	t.rows = append(t.rows, cells)
+}
+
+// tableFormats lists the names of supported table formats
+var tableFormats = []string{"csv", "markdown", "json", "yaml"}
+
+// Utilities
+
+// encode encodes the table in one of the table formats
+func (t table) encode(format string) ([]byte, error) {
+	switch format {
+	case "csv":
+		return t.encodeCSV()
+	case "markdown", "md":
+		return t.encodeMarkdown(), nil
+	case "json":
+		return t.encodeJSON()
+	case "yaml":
+		return yaml.Marshal(t.records())
+	}
+	return nil, fmt.Errorf("unknown format %q, use %s", format, strings.Join(tableFormats, ", "))
+}
+
+// records returns the rows as ordered key value lists
+func (t table) records() []yaml.MapSlice {
+	records := make([]yaml.MapSlice, len(t.rows))
+	for i, row := range t.rows {
+		for j, cell := range row {
+			records[i] = append(records[i], yaml.MapItem{Key: t.columns[j], Value: cell})
+		}
+	}
+	return records
+}
+
+// encodeCSV encodes the table as csv with a header row
+func (t table) encodeCSV() ([]byte, error) {
+	var b bytes.Buffer
+	writer := csv.NewWriter(&b)
+	writer.Write(t.columns)
+	for _, row := range t.rows {
+		writer.Write(formatCells(row))
+	}
+	writer.Flush()
+	return b.Bytes(), writer.Error()
+}
+
+// encodeMarkdown encodes the table as a markdown table
+func (t table) encodeMarkdown() []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "| %s |\n|%s\n", strings.Join(t.columns, " | "), strings.Repeat("---|", len(t.columns)))
+	for _, row := range t.rows {
+		fmt.Fprintf(&b, "| %s |\n", strings.Join(formatCells(row), " | "))
+	}
+	return b.Bytes()
+}
+
+// encodeJSON encodes the table as a json array of objects keeping the column order
+func (t table) encodeJSON() ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteString("[\n")
+	for i, row := range t.rows {
+		b.WriteString("  {")
+		for j, cell := range row {
+			key, _ := json.Marshal(t.columns[j])
+			value, err := json.Marshal(jsonCell(cell))
+			if err != nil {
+				return nil, err
+			}
+			if j > 0 {
+				b.WriteString(", ")
+			}
+			fmt.Fprintf(&b, "%s: %s", key, value)
+		}
+		b.WriteString("}")
+		if i < len(t.rows)-1 {
+			b.WriteString(",")
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString("]\n")
+	return b.Bytes(), nil
+}
+
+// jsonCell replaces infinite and undefined floats, which json cannot encode, by null
+func jsonCell(cell interface{}) interface{} {
+	if value, ok := cell.(float64); ok && (math.IsInf(value, 0) || math.IsNaN(value)) {
+		return nil
+	}
+	return cell
+}
+
+// formatCells formats table cells as text, fractional floats with two decimals
+func formatCells(row []interface{}) []string {
+	cells := make([]string, len(row))
+	for i, cell := range row {
+		if value, ok := cell.(float64); ok && value != math.Trunc(value) {
+			cells[i] = fmt.Sprintf("%.2f", value)
+		} else {
+			cells[i] = fmt.Sprint(cell)
+		}
+	}
+	return cells
+}
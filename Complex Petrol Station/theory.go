@@ -0,0 +1,325 @@
+package main
+
+import (
+	"fmt"
+	"goenv/Services"
+	"io"
+	"math"
+	"os"
+	"strings"
+)
+
+// Initializations
+
+// queueModel describes a group of identical servers sharing the arrivals of one class of cars
+type queueModel struct {
+	group       string
+	servers     int
+	arrivalRate float64 // cars per ms
+	meanService float64 // ms
+	arrivalSCV  float64 // squared coefficient of variation of interarrival times
+	serviceSCV  float64 // squared coefficient of variation of service times
+}
+
+// queueEstimate is the analytical estimate of a queue model
+type queueEstimate struct {
+	queueModel
+	utilization float64
+	waitProb    float64
+	waitMMc     float64 // Erlang-C mean wait
+	waitGGc     float64 // Allen-Cunneen mean wait
+}
+
+// theoryColumns lists the columns of the estimates table
+var theoryColumns = []string{"group", "servers", "arrival_rate", "mean_service", "utilization",
+	"wait_probability", "wait_mmc", "wait_allen_cunneen", "stable"}
+
+// Commands
+
+// theoryCommand prints analytical queueing estimates, optionally next to a simulation
+func theoryCommand(args []string) error {
+	fs := newFlagSet("theory")
+	var cf configFlags
+	cf.register(fs)
+	simulateRun := fs.Bool("simulate", false, "run a simulation and show its queue times side by side")
+	out := fs.String("out", "-", "path of the table, - for stdout")
+	format := fs.String("format", "markdown", "table format: csv, markdown, json or yaml")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	config, err := cf.load()
+	if err != nil {
+		return err
+	}
+	if _, err = (table{}).encode(*format); err != nil {
+		return configError{err}
+	}
+	station, err := config.newStation()
+	if err != nil {
+		return configError{err}
+	}
+	estimates := estimateQueues(queueModels(config, station))
+	t := table{columns: append([]string{}, theoryColumns...)}
+	var simulated map[string]StationStats
+	if *simulateRun {
+		t.columns = append(t.columns, "simulated_avg_queue_time")
+		station.Log = io.Discard
+		stats := simulate(station, config)
+		simulated = make(map[string]StationStats)
+		for _, group := range stats.groups() {
+			simulated[strings.ToLower(group.Name)] = group.Stats
+		}
+	}
+	for _, e := range estimates {
+		row := e.row()
+		if simulated != nil {
+			row = append(row, pooledQueueTime(simulated, e.group))
+		}
+		t.addRow(row...)
+	}
+	warnUnstable(os.Stderr, estimates)
+	return writeTable(t, *format, *out)
+}
+
+// Utilities
+
+// queueModels derives the queueing models of every fuel group and the register pool
+//
+// A stand stays occupied until its car has paid, so stand service covers fueling, the
+// estimated register wait and payment. Registers of an unstable class are flagged on
+// their own and their wait is left out.
+// Cars are split between register classes by their preferences, eligibility is not modelled.
+// Fuels sharing multi-product stands are pooled into one group served by all their stands.
+// Cars washing between fueling and payment leave their stand before paying.
+func queueModels(config Config, station *Services.Station) []queueModel {
+	meanGap, gapSCV := uniformMoments(config.Cars.ArrivalTimeMin, config.Cars.ArrivalTimeMax)
+	shares := make(map[Services.FuelType]float64)
+	for _, fuel := range Services.FuelTypes {
+		shares[fuel] = 1 / float64(len(Services.FuelTypes))
+	}
+	if station.Trace != nil {
+		meanGap, gapSCV, shares = traceMoments(station.Trace, station.TraceScale)
+	}
+	// Register classes and the payment time mixed over them
+	type registerModel struct {
+		group               string
+		servers             int
+		share, mean, second float64
+	}
+	staffedMean, staffedSecond := paymentMoments(config.Payments, config.Registers.HandleTimeMin, config.Registers.HandleTimeMax, true)
+	registers := []registerModel{{"registers", config.Registers.Count, 1, staffedMean, staffedSecond}}
+	for _, class := range Services.SelfServiceClasses {
+		setup := config.registerClasses()[class]
+		if setup.Count == 0 {
+			continue
+		}
+		registers[0].group = "staffed"
+		registers[0].share -= setup.Preference
+		mean, second := paymentMoments(config.Payments, setup.HandleTimeMin, setup.HandleTimeMax, false)
+		registers = append(registers, registerModel{string(class), setup.Count, setup.Preference, mean, second})
+	}
+	var registerModels []queueModel
+	for _, r := range registers {
+		scv := 0.0
+		if r.mean > 0 {
+			scv = r.second/(r.mean*r.mean) - 1
+		}
+		registerModels = append(registerModels, queueModel{
+			group:       r.group,
+			servers:     r.servers,
+			arrivalRate: r.share / meanGap,
+			meanService: r.mean,
+			arrivalSCV:  r.share*gapSCV + 1 - r.share,
+			serviceSCV:  scv,
+		})
+	}
+	// Cars hold their stand through the register wait, taken as a constant added to the payment
+	payMean, paySecond := 0.0, 0.0
+	for i, e := range estimateQueues(registerModels) {
+		r := registers[i]
+		wait := e.waitGGc
+		if math.IsInf(wait, 1) {
+			wait = 0
+		}
+		payMean += r.share * (r.mean + wait)
+		paySecond += r.share * (r.second + 2*wait*r.mean + wait*wait)
+	}
+	// Share of cars paying while they hold their stand
+	held := 1.0
+	if pipeline := config.pipeline(); pipeline[1] == Services.StageWash && config.Wash.enabled() {
+		held = 1 - config.Wash.Share
+	}
+	var models []queueModel
+	for _, pool := range config.fuelPools() {
+		var names []string
+		share, mean, second := 0.0, 0.0, 0.0
+		for _, fuel := range pool {
+			names = append(names, strings.ToLower(string(fuel)))
+			stand := config.stands()[fuel]
+			fuelMean, fuelSCV := uniformMoments(stand.ServeTimeMin, stand.ServeTimeMax)
+			// Fueling and payment are independent
+			weight := max(shares[fuel], 1e-12)
+			share += shares[fuel]
+			mean += weight * (fuelMean + held*payMean)
+			second += weight * ((fuelSCV+1)*fuelMean*fuelMean + held*(2*fuelMean*payMean+paySecond))
+		}
+		weights := max(share, 1e-12*float64(len(pool)))
+		mean, second = mean/weights, second/weights
+		models = append(models, queueModel{
+			group:       strings.Join(names, "+"),
+			servers:     config.poolStands(pool),
+			arrivalRate: share / meanGap,
+			meanService: mean,
+			// Random splitting of the arrival stream
+			arrivalSCV: share*gapSCV + 1 - share,
+			serviceSCV: second/(mean*mean) - 1,
+		})
+	}
+	models = append(models, registerModels...)
+	if config.Wash.enabled() {
+		mean, scv := uniformMoments(config.Wash.TimeMin, config.Wash.TimeMax)
+		share := config.Wash.Share
+		models = append(models, queueModel{
+			group:       "wash",
+			servers:     config.Wash.Bays,
+			arrivalRate: share / meanGap,
+			meanService: mean,
+			arrivalSCV:  share*gapSCV + 1 - share,
+			serviceSCV:  scv,
+		})
+	}
+	return models
+}
+
+// estimateQueues computes Erlang-C and Allen-Cunneen estimates of the models
+func estimateQueues(models []queueModel) []queueEstimate {
+	estimates := make([]queueEstimate, len(models))
+	for i, m := range models {
+		e := queueEstimate{queueModel: m}
+		offered := m.arrivalRate * m.meanService
+		if m.servers > 0 {
+			e.utilization = offered / float64(m.servers)
+		} else if offered > 0 {
+			e.utilization = math.Inf(1)
+		}
+		if e.utilization < 1 {
+			e.waitProb = erlangC(m.servers, offered)
+			e.waitMMc = e.waitProb / (float64(m.servers)/m.meanService - m.arrivalRate)
+			e.waitGGc = e.waitMMc * (m.arrivalSCV + m.serviceSCV) / 2
+		} else {
+			e.waitProb, e.waitMMc, e.waitGGc = 1, math.Inf(1), math.Inf(1)
+		}
+		estimates[i] = e
+	}
+	return estimates
+}
+
+// row returns the cells of the estimate in the order of theoryColumns, unstable waits are infinite
+func (e queueEstimate) row() []interface{} {
+	return []interface{}{e.group, e.servers, e.arrivalRate, e.meanService, e.utilization,
+		e.waitProb, e.waitMMc, e.waitGGc, e.utilization < 1}
+}
+
+// erlangC returns the probability of waiting in an M/M/c queue with the offered load
+func erlangC(servers int, offered float64) float64 {
+	if servers == 0 || offered == 0 {
+		return 0
+	}
+	// Erlang-B recursion converted to Erlang-C
+	b := 1.0
+	for k := 1; k <= servers; k++ {
+		b = offered * b / (float64(k) + offered*b)
+	}
+	rho := offered / float64(servers)
+	return b / (1 - rho + rho*b)
+}
+
+// uniformMoments returns the mean and squared coefficient of variation of the random times in [min, max)
+func uniformMoments(min, max int) (float64, float64) {
+	k := float64(max - min)
+	mean := float64(min) + (k-1)/2
+	if mean <= 0 {
+		return 0, 0
+	}
+	return mean, (k*k - 1) / 12 / (mean * mean)
+}
+
+// pooledQueueTime returns the simulated queue time of a group, averaged over the fuels of a pooled group
+func pooledQueueTime(simulated map[string]StationStats, group string) int {
+	if stats, ok := simulated[group]; ok {
+		return stats.AvgQueueTime
+	}
+	cars, total := 0, 0
+	for _, name := range strings.Split(group, "+") {
+		cars += simulated[name].TotalCars
+		total += simulated[name].AvgQueueTime * simulated[name].TotalCars
+	}
+	if cars == 0 {
+		return 0
+	}
+	return total / cars
+}
+
+// paymentMoments returns the mean and second moment of a whole payment with attempts taking min to max
+//
+// With payment methods declined attempts are repeated and at staffed registers
+// every method takes its own time instead.
+func paymentMoments(payments PaymentsConfig, min, max int, staffed bool) (float64, float64) {
+	mean, scv := uniformMoments(min, max)
+	if !payments.enabled() {
+		return mean, (scv + 1) * mean * mean
+	}
+	total := 0.0
+	for _, method := range payments.methods() {
+		total += method.Share
+	}
+	payMean, paySecond := 0.0, 0.0
+	for _, method := range payments.methods() {
+		if method.Share == 0 {
+			continue
+		}
+		m, s := mean, scv
+		if staffed {
+			m, s = uniformMoments(method.TimeMin, method.TimeMax)
+		}
+		// Number of attempts, the first one plus the declined ones up to the retries
+		attempts, attemptsSecond := 0.0, 0.0
+		for k := 0; k <= method.Retries; k++ {
+			p := math.Pow(method.FailureRate, float64(k))
+			attempts += p
+			attemptsSecond += float64(2*k+1) * p
+		}
+		// Sum of a random number of independent attempts
+		variance := attempts*s*m*m + (attemptsSecond-attempts*attempts)*m*m
+		share := method.Share / total
+		payMean += share * attempts * m
+		paySecond += share * (variance + attempts*attempts*m*m)
+	}
+	return payMean, paySecond
+}
+
+// traceMoments returns the mean and squared coefficient of variation of recorded gaps and the fuel shares
+func traceMoments(trace []Services.TraceRecord, scale float64) (float64, float64, map[Services.FuelType]float64) {
+	shares := make(map[Services.FuelType]float64)
+	for _, record := range trace {
+		shares[record.Fuel] += 1 / float64(len(trace))
+	}
+	var gaps []float64
+	for i := 1; i < len(trace); i++ {
+		gaps = append(gaps, math.Max(trace[i].Arrival.Sub(trace[i-1].Arrival).Seconds()*scale, 0))
+	}
+	mean, sd, _ := confidenceInterval(gaps)
+	if mean <= 0 {
+		return math.Inf(1), 0, shares
+	}
+	return mean, sd * sd / (mean * mean), shares
+}
+
+// warnUnstable reports groups whose utilization makes their queues grow without bound
+func warnUnstable(w io.Writer, estimates []queueEstimate) {
+	for _, e := range estimates {
+		if e.utilization >= 1 {
+			fmt.Fprintf(w, "Warning: %s utilization is %.2f, its queue is unstable\n", e.group, e.utilization)
+		}
+	}
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"encoding/json"
+	"goenv/Services"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestTheoryTableUnstableJSON(t *testing.T) {
+	estimates := estimateQueues([]queueModel{
+		{group: "gas", servers: 2, arrivalRate: 0.01, meanService: 50, arrivalSCV: 1, serviceSCV: 1},
+		{group: "registers", servers: 1, arrivalRate: 0.1, meanService: 20, arrivalSCV: 1, serviceSCV: 1},
+		{group: "wash", servers: 0, arrivalRate: 0.1, meanService: 20},
+	})
+	tbl := table{columns: theoryColumns}
+	for _, e := range estimates {
+		tbl.addRow(e.row()...)
+	}
+	data, err := tbl.encode("json")
+	if err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	var records []map[string]interface{}
+	if err := json.Unmarshal(data, &records); err != nil {
+		t.Fatalf("decode %s: %v", data, err)
+	}
+	if len(records) != 3 {
+		t.Fatalf("got %d records, want 3", len(records))
+	}
+	if records[0]["wait_mmc"] == nil || records[0]["stable"] != true {
+		t.Errorf("stable group = %v, want finite waits", records[0])
+	}
+	for _, record := range records[1:] {
+		if record["wait_mmc"] != nil || record["wait_allen_cunneen"] != nil || record["stable"] != false {
+			t.Errorf("unstable group = %v, want null waits", record)
+		}
+	}
+	if records[2]["utilization"] != nil {
+		t.Errorf("group without servers has utilization %v, want null", records[2]["utilization"])
+	}
+}
+
+func TestQueueModelsStandHoldsThroughRegisterWait(t *testing.T) {
+	config := defaultConfig()
+	config.Cars.ArrivalTimeMin, config.Cars.ArrivalTimeMax = 1, 9
+	station, err := config.newStation()
+	if err != nil {
+		t.Fatal(err)
+	}
+	models := queueModels(config, station)
+	groups := make(map[string]queueModel)
+	for _, m := range models {
+		groups[m.group] = m
+	}
+	registers := estimateQueues([]queueModel{groups["registers"]})[0]
+	if registers.utilization >= 1 || registers.waitGGc <= 0 {
+		t.Fatalf("registers = %+v, want a stable pool with waiting", registers)
+	}
+	fuelMean, _ := uniformMoments(config.Stations.Gas.ServeTimeMin, config.Stations.Gas.ServeTimeMax)
+	payMean, _ := uniformMoments(config.Registers.HandleTimeMin, config.Registers.HandleTimeMax)
+	want := fuelMean + registers.waitGGc + payMean
+	if got := groups["gas"].meanService; math.Abs(got-want) > 1e-9 {
+		t.Errorf("gas mean service = %v, want fueling, register wait and payment %v", got, want)
+	}
+}
+
+func TestErlangC(t *testing.T) {
+	tests := []struct {
+		servers int
+		offered float64
+		want    float64
+	}{
+		{0, 1, 0},
+		{1, 0, 0},
+		{1, 0.5, 0.5},
+		{2, 1, 1.0 / 3},
+		{3, 2, 4.0 / 9},
+		{5, 4, 0.5541},
+	}
+	for _, tt := range tests {
+		if got := erlangC(tt.servers, tt.offered); math.Abs(got-tt.want) > 1e-3 {
+			t.Errorf("erlangC(%d, %v) = %v, want %v", tt.servers, tt.offered, got, tt.want)
+		}
+	}
+}
+
+func TestEstimateQueues(t *testing.T) {
+	// M/M/1 with utilization 0.5 waits as long as it serves, deterministic service halves the wait
+	estimates := estimateQueues([]queueModel{
+		{group: "mm1", servers: 1, arrivalRate: 0.05, meanService: 10, arrivalSCV: 1, serviceSCV: 1},
+		{group: "md1", servers: 1, arrivalRate: 0.05, meanService: 10, arrivalSCV: 1, serviceSCV: 0},
+		{group: "full", servers: 1, arrivalRate: 0.1, meanService: 10, arrivalSCV: 1, serviceSCV: 1},
+	})
+	tests := []struct {
+		utilization, waitMMc, waitGGc float64
+	}{
+		{0.5, 10, 10},
+		{0.5, 10, 5},
+		{1, math.Inf(1), math.Inf(1)},
+	}
+	for i, tt := range tests {
+		e := estimates[i]
+		if math.Abs(e.utilization-tt.utilization) > 1e-9 || e.waitMMc != tt.waitMMc && math.Abs(e.waitMMc-tt.waitMMc) > 1e-9 ||
+			e.waitGGc != tt.waitGGc && math.Abs(e.waitGGc-tt.waitGGc) > 1e-9 {
+			t.Errorf("%s: utilization %v, waits %v and %v, want %v, %v and %v", e.group,
+				e.utilization, e.waitMMc, e.waitGGc, tt.utilization, tt.waitMMc, tt.waitGGc)
+		}
+	}
+}
+
+func TestUniformMoments(t *testing.T) {
+	tests := []struct {
+		min, max  int
+		mean, scv float64
+	}{
+		{1, 2, 1, 0},
+		{1, 5, 2.5, 0.2},
+		{10, 30, 19.5, 399.0 / 12 / (19.5 * 19.5)},
+		{0, 1, 0, 0},
+	}
+	for _, tt := range tests {
+		mean, scv := uniformMoments(tt.min, tt.max)
+		if math.Abs(mean-tt.mean) > 1e-9 || math.Abs(scv-tt.scv) > 1e-9 {
+			t.Errorf("uniformMoments(%d, %d) = %v, %v, want %v, %v", tt.min, tt.max, mean, scv, tt.mean, tt.scv)
+		}
+	}
+}
+
+func TestTraceMoments(t *testing.T) {
+	start := time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC)
+	trace := []Services.TraceRecord{
+		{Arrival: start, Fuel: Services.Gas},
+		{Arrival: start.Add(10 * time.Second), Fuel: Services.Gas},
+		{Arrival: start.Add(30 * time.Second), Fuel: Services.Diesel},
+		{Arrival: start.Add(60 * time.Second), Fuel: Services.Gas},
+	}
+	tests := []struct {
+		name      string
+		trace     []Services.TraceRecord
+		scale     float64
+		mean, scv float64
+	}{
+		{"recorded", trace, 1, 20, 0.25},
+		{"scaled", trace, 0.5, 10, 0.25},
+		{"single", trace[:1], 1, math.Inf(1), 0},
+	}
+	for _, tt := range tests {
+		mean, scv, _ := traceMoments(tt.trace, tt.scale)
+		if mean != tt.mean && math.Abs(mean-tt.mean) > 1e-9 || math.Abs(scv-tt.scv) > 1e-9 {
+			t.Errorf("%s: traceMoments = %v, %v, want %v, %v", tt.name, mean, scv, tt.mean, tt.scv)
+		}
+	}
+	_, _, shares := traceMoments(trace, 1)
+	if shares[Services.Gas] != 0.75 || shares[Services.Diesel] != 0.25 {
+		t.Errorf("fuel shares = %v, want gas 0.75 and diesel 0.25", shares)
+	}
+}
+
+func TestPooledQueueTime(t *testing.T) {
+	simulated := map[string]StationStats{
+		"gas":    {TotalCars: 30, AvgQueueTime: 10},
+		"diesel": {TotalCars: 10, AvgQueueTime: 50},
+	}
+	tests := []struct {
+		group string
+		want  int
+	}{
+		{"gas", 10},
+		{"gas+diesel", 20},
+		{"lpg", 0},
+	}
+	for _, tt := range tests {
+		if got := pooledQueueTime(simulated, tt.group); got != tt.want {
+			t.Errorf("pooledQueueTime(%s) = %d, want %d", tt.group, got, tt.want)
+		}
+	}
+}
+
+func TestPaymentMoments(t *testing.T) {
+	card := func(failureRate float64, retries int) PaymentsConfig {
+		return PaymentsConfig{Card: PaymentMethodConfig{Share: 1, TimeMin: 4, TimeMax: 5, FailureRate: failureRate, Retries: retries}}
+	}
+	tests := []struct {
+		name         string
+		payments     PaymentsConfig
+		min, max     int
+		staffed      bool
+		mean, second float64
+	}{
+		{"without methods", PaymentsConfig{}, 1, 5, true, 2.5, 7.5},
+		{"never declined", card(0, 3), 1, 5, false, 2.5, 7.5},
+		// One or two attempts of 4 ms with equal chances
+		{"retried at a staffed register", card(0.5, 1), 1, 5, true, 6, 40},
+		{"retried at a kiosk", card(0.5, 1), 4, 5, false, 6, 40},
+		{"mixed methods", PaymentsConfig{
+			Cash: PaymentMethodConfig{Share: 1, TimeMin: 2, TimeMax: 3},
+			Card: PaymentMethodConfig{Share: 3, TimeMin: 4, TimeMax: 5},
+		}, 1, 5, true, 0.25*2 + 0.75*4, 0.25*4 + 0.75*16},
+	}
+	for _, tt := range tests {
+		mean, second := paymentMoments(tt.payments, tt.min, tt.max, tt.staffed)
+		if math.Abs(mean-tt.mean) > 1e-9 || math.Abs(second-tt.second) > 1e-9 {
+			t.Errorf("%s: moments = %v, %v, want %v, %v", tt.name, mean, second, tt.mean, tt.second)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"fmt"
+	"goenv/Services"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Initializations
+
+// sampler records snapshots of a running station at a fixed interval
+type sampler struct {
+	mu       sync.Mutex
+	interval time.Duration
+	samples  []Services.Snapshot
+}
+
+// newSampler creates a sampler taking a snapshot every interval ms
+func newSampler(interval int) *sampler {
+	return &sampler{interval: time.Duration(interval) * time.Millisecond}
+}
+
+// Routines
+
+// samplingRoutine records station snapshots until stop is closed
+func (s *sampler) samplingRoutine(station *Services.Station, stop <-chan struct{}, done func()) {
+	defer done()
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for {
+		s.mu.Lock()
+		s.samples = append(s.samples, station.Snapshot())
+		s.mu.Unlock()
+		select {
+		case <-stop:
+			// Final state of the drained station
+			s.mu.Lock()
+			s.samples = append(s.samples, station.Snapshot())
+			s.mu.Unlock()
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Utilities
+
+// snapshots returns the samples recorded so far
+func (s *sampler) snapshots() []Services.Snapshot {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Services.Snapshot(nil), s.samples...)
+}
+
+// seriesTable lays out samples with one row per sample and queue and busy columns per stand and register
+func seriesTable(samples []Services.Snapshot) table {
+	t := table{columns: []string{"time", "cars_arrived", "cars_served", "arrival_backlog", "building_queue"}}
+	if len(samples) == 0 {
+		return t
+	}
+	for _, stand := range samples[0].Stands {
+		name := fmt.Sprintf("stand_%d_%s", stand.Id, strings.ToLower(strings.ReplaceAll(standFuels(stand), "+", "_")))
+		t.columns = append(t.columns, name+"_queue", name+"_busy")
+	}
+	for _, lane := range samples[0].Lanes {
+		name := fmt.Sprintf("lane_%d", lane.Id)
+		t.columns = append(t.columns, name+"_queue", name+"_blocked")
+	}
+	if len(samples[0].WashBays) > 0 {
+		t.columns = append(t.columns, "wash_queue")
+	}
+	for _, bay := range samples[0].WashBays {
+		t.columns = append(t.columns, fmt.Sprintf("wash_%d_busy", bay.Id))
+	}
+	for _, register := range samples[0].Registers {
+		name := fmt.Sprintf("register_%d", register.Id)
+		t.columns = append(t.columns, name+"_queue", name+"_busy", name+"_open")
+	}
+	// Prices only change with a pricing strategy
+	priced := false
+	for _, sample := range samples {
+		for _, fuel := range sample.Fuels {
+			priced = priced || fuel.Price > 0
+		}
+	}
+	if priced {
+		for _, fuel := range samples[0].Fuels {
+			t.columns = append(t.columns, "price_"+strings.ToLower(string(fuel.Fuel)))
+		}
+	}
+	for _, sample := range samples {
+		row := []interface{}{sample.Time, sample.CarsArrived, sample.CarsServed, sample.ArrivalBacklog, sample.BuildingQueue}
+		for _, stand := range sample.Stands {
+			row = append(row, stand.Queue, busy(stand))
+		}
+		for _, lane := range sample.Lanes {
+			row = append(row, lane.Queue, lane.Blocked)
+		}
+		if len(sample.WashBays) > 0 {
+			row = append(row, sample.WashQueue)
+		}
+		for _, bay := range sample.WashBays {
+			row = append(row, busy(bay))
+		}
+		for _, register := range sample.Registers {
+			row = append(row, register.Queue, busy(register), staffed(register))
+		}
+		if priced {
+			for _, fuel := range sample.Fuels {
+				row = append(row, fuel.Price)
+			}
+		}
+		t.addRow(row...)
+	}
+	return t
+}
+
+// staffed returns 1 when the register has a cashier and 0 when closed
+func staffed(state Services.QueueState) int {
+	if state.Closed {
+		return 0
+	}
+	return 1
+}
+
+// standFuels returns the fuels sold at a stand joined by +
+func standFuels(state Services.QueueState) string {
+	if len(state.Fuels) == 0 {
+		return string(state.Fuel)
+	}
+	names := make([]string, len(state.Fuels))
+	for i, fuel := range state.Fuels {
+		names[i] = string(fuel)
+	}
+	return strings.Join(names, "+")
+}
+
+// busy returns 1 when the stand or register is serving a car and 0 when idle
+func busy(state Services.QueueState) int {
+	if state.Serving < 0 {
+		return 0
+	}
+	return 1
+}
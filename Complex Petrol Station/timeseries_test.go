@@ -0,0 +1,87 @@
+package main
+
+import (
+	"goenv/Services"
+	"strings"
+	"testing"
+)
+
+func TestSeriesTable(t *testing.T) {
+	sample := func(time int64, serving int, price float64) Services.Snapshot {
+		return Services.Snapshot{
+			Time: time, CarsArrived: int(time), CarsServed: int(time) / 2,
+			Stands: []Services.QueueState{
+				{Id: 1, Fuel: Services.Gas, Queue: 2, Serving: serving},
+				{Id: 2, Fuels: []Services.FuelType{Services.Gas, Services.Diesel}, Queue: 0, Serving: -1},
+			},
+			Registers: []Services.QueueState{{Id: 1, Queue: 1, Serving: -1, Closed: true}},
+			Fuels:     []Services.FuelState{{Fuel: Services.Gas, Price: price}, {Fuel: Services.Diesel}},
+		}
+	}
+	tests := []struct {
+		name    string
+		samples []Services.Snapshot
+		columns string
+		rows    []string
+	}{
+		{"empty", nil, "time,cars_arrived,cars_served,arrival_backlog,building_queue", nil},
+		{
+			"unpriced",
+			[]Services.Snapshot{sample(10, 4, 0), sample(20, -1, 0)},
+			"time,cars_arrived,cars_served,arrival_backlog,building_queue,stand_1_gas_queue,stand_1_gas_busy," +
+				"stand_2_gas_diesel_queue,stand_2_gas_diesel_busy,register_1_queue,register_1_busy,register_1_open",
+			[]string{"10 10 5 0 0 2 1 0 0 1 0 0", "20 20 10 0 0 2 0 0 0 1 0 0"},
+		},
+		{
+			"priced",
+			[]Services.Snapshot{sample(10, 4, 0), sample(20, 4, 1.5)},
+			"time,cars_arrived,cars_served,arrival_backlog,building_queue,stand_1_gas_queue,stand_1_gas_busy," +
+				"stand_2_gas_diesel_queue,stand_2_gas_diesel_busy,register_1_queue,register_1_busy,register_1_open," +
+				"price_gas,price_diesel",
+			[]string{"10 10 5 0 0 2 1 0 0 1 0 0 0 0", "20 20 10 0 0 2 1 0 0 1 0 0 1.50 0"},
+		},
+	}
+	for _, tt := range tests {
+		series := seriesTable(tt.samples)
+		if got := strings.Join(series.columns, ","); got != tt.columns {
+			t.Errorf("%s: columns = %s, want %s", tt.name, got, tt.columns)
+		}
+		if len(series.rows) != len(tt.rows) {
+			t.Errorf("%s: %d rows, want %d", tt.name, len(series.rows), len(tt.rows))
+			continue
+		}
+		for i, row := range series.rows {
+			if len(row) != len(series.columns) {
+				t.Errorf("%s: row %d has %d cells for %d columns", tt.name, i, len(row), len(series.columns))
+			}
+			if got := strings.Join(formatCells(row), " "); got != tt.rows[i] {
+				t.Errorf("%s: row %d = %s, want %s", tt.name, i, got, tt.rows[i])
+			}
+		}
+	}
+}
+
+func TestQueueStateCells(t *testing.T) {
+	tests := []struct {
+		name          string
+		state         Services.QueueState
+		busy, staffed int
+		fuels         string
+	}{
+		{"idle", Services.QueueState{Serving: -1, Fuel: Services.LPG}, 0, 1, "LPG"},
+		{"serving first car", Services.QueueState{Serving: 0, Fuel: Services.Gas}, 1, 1, "gas"},
+		{"closed", Services.QueueState{Serving: -1, Closed: true}, 0, 0, ""},
+		{"dispenser", Services.QueueState{Serving: 3, Fuels: []Services.FuelType{Services.Gas, Services.Diesel}}, 1, 1, "gas+diesel"},
+	}
+	for _, tt := range tests {
+		if got := busy(tt.state); got != tt.busy {
+			t.Errorf("%s: busy = %d, want %d", tt.name, got, tt.busy)
+		}
+		if got := staffed(tt.state); got != tt.staffed {
+			t.Errorf("%s: staffed = %d, want %d", tt.name, got, tt.staffed)
+		}
+		if got := standFuels(tt.state); got != tt.fuels {
+			t.Errorf("%s: standFuels = %s, want %s", tt.name, got, tt.fuels)
+		}
+	}
+}
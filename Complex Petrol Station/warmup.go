@@ -0,0 +1,102 @@
+package main
+
+import (
+	"goenv/Services"
+	"math"
+	"sort"
+)
+
+// Variables
+
+// MSER batch size
+
+var mserBatch = 5
+
+// Initializations
+
+// WarmupConfig is a struct for the configuration of the warm-up period
+type WarmupConfig struct {
+	Cars int  `yaml:"cars" json:"cars"` // first cars excluded from statistics
+	Time int  `yaml:"time" json:"time"` // cars arriving earlier than this are excluded
+	Auto bool `yaml:"auto" json:"auto"` // estimate the warm-up with MSER-5
+}
+
+// Utilities
+
+// cutoff determines which of the cars ordered by arrival belong to the warm-up
+func (warmup WarmupConfig) cutoff(cars []*Services.Car) WarmupCutoff {
+	var cutoff WarmupCutoff
+	switch {
+	case warmup.Auto:
+		cutoff = WarmupCutoff{Method: "mser5", Cars: mser(queueTimes(cars), mserBatch)}
+	case warmup.Cars > 0:
+		cutoff = WarmupCutoff{Method: "cars", Cars: min(warmup.Cars, len(cars))}
+	case warmup.Time > 0:
+		cutoff = WarmupCutoff{Method: "time"}
+		for cutoff.Cars < len(cars) && int(cars[cutoff.Cars].ArrivalTime) < warmup.Time {
+			cutoff.Cars++
+		}
+	default:
+		return cutoff
+	}
+	if cutoff.Cars < len(cars) {
+		cutoff.Time = int(cars[cutoff.Cars].ArrivalTime)
+	}
+	return cutoff
+}
+
+// sortByArrival orders the cars by arrival time, cars arriving at once by id
+//
+// Ids follow arrival only for the planned cars, cars drawn in by a lower price get theirs past them.
+func sortByArrival(cars []*Services.Car) {
+	sort.Slice(cars, func(i, j int) bool {
+		if cars[i].ArrivalTime != cars[j].ArrivalTime {
+			return cars[i].ArrivalTime < cars[j].ArrivalTime
+		}
+		return cars[i].ID < cars[j].ID
+	})
+}
+
+// queueTimes returns the total time every car spent queueing
+func queueTimes(cars []*Services.Car) []float64 {
+	times := make([]float64, len(cars))
+	for i, car := range cars {
+		times[i] = float64(car.StandQueueTime + car.RegisterQueueTime)
+	}
+	return times
+}
+
+// mser estimates the number of warm-up observations with the MSER heuristic over batch means
+//
+// The truncation point minimizes the squared standard error of the remaining
+// batch means and is searched only in the first half of the series.
+func mser(series []float64, batch int) int {
+	var means []float64
+	for i := 0; i+batch <= len(series); i += batch {
+		sum := 0.0
+		for _, value := range series[i : i+batch] {
+			sum += value
+		}
+		means = append(means, sum/float64(batch))
+	}
+	best, bestValue := 0, math.Inf(1)
+	for d := 0; d <= len(means)/2; d++ {
+		rest := means[d:]
+		if len(rest) < 2 {
+			break
+		}
+		mean := 0.0
+		for _, value := range rest {
+			mean += value
+		}
+		mean /= float64(len(rest))
+		squares := 0.0
+		for _, value := range rest {
+			squares += (value - mean) * (value - mean)
+		}
+		if value := squares / float64(len(rest)*len(rest)); value < bestValue {
+			best, bestValue = d, value
+		}
+	}
+	return best * batch
+}
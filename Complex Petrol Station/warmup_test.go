@@ -0,0 +1,93 @@
+package main
+
+import (
+	"goenv/Services"
+	"testing"
+	"time"
+)
+
+func TestSortByArrival(t *testing.T) {
+	// Car 5 was drawn in by a lower price at 20 ms, after the planned cars got their ids
+	cars := []*Services.Car{
+		{ID: 0, ArrivalTime: 0},
+		{ID: 1, ArrivalTime: 10},
+		{ID: 2, ArrivalTime: 30},
+		{ID: 3, ArrivalTime: 40},
+		{ID: 4, ArrivalTime: 20},
+		{ID: 5, ArrivalTime: 20},
+	}
+	sortByArrival(cars)
+	want := []int{0, 1, 4, 5, 2, 3}
+	for i, car := range cars {
+		if car.ID != want[i] {
+			t.Fatalf("car %d has id %d, want order %v", i, car.ID, want)
+		}
+	}
+}
+
+func TestWarmupTimeCutoffAfterPricedCars(t *testing.T) {
+	cars := []*Services.Car{
+		{ID: 0, ArrivalTime: 0},
+		{ID: 1, ArrivalTime: 10},
+		{ID: 2, ArrivalTime: 30},
+		{ID: 3, ArrivalTime: 5},
+	}
+	sortByArrival(cars)
+	cutoff := WarmupConfig{Time: 20}.cutoff(cars)
+	if cutoff.Method != "time" || cutoff.Cars != 3 || cutoff.Time != 30 {
+		t.Errorf("cutoff = %+v, want 3 cars up to 30 ms", cutoff)
+	}
+}
+
+func TestWarmupCutoff(t *testing.T) {
+	var cars []*Services.Car
+	for i := 0; i < 10; i++ {
+		cars = append(cars, &Services.Car{ID: i, ArrivalTime: time.Duration(i * 10)})
+	}
+	tests := []struct {
+		name   string
+		warmup WarmupConfig
+		want   WarmupCutoff
+	}{
+		{"none", WarmupConfig{}, WarmupCutoff{}},
+		{"cars", WarmupConfig{Cars: 3}, WarmupCutoff{Method: "cars", Cars: 3, Time: 30}},
+		{"more cars than arrived", WarmupConfig{Cars: 20}, WarmupCutoff{Method: "cars", Cars: 10}},
+		{"time", WarmupConfig{Time: 45}, WarmupCutoff{Method: "time", Cars: 5, Time: 50}},
+	}
+	for _, tt := range tests {
+		if got := tt.warmup.cutoff(cars); got != tt.want {
+			t.Errorf("%s: cutoff = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestMser(t *testing.T) {
+	steady := make([]float64, 100)
+	for i := range steady {
+		steady[i] = float64(10 + i%2)
+	}
+	// Rising transient of 20 observations before the steady state
+	transient := make([]float64, 100)
+	for i := range transient {
+		transient[i] = float64(10 + i%2)
+		if i < 20 {
+			transient[i] = float64(i) / 2
+		}
+	}
+	tests := []struct {
+		name   string
+		series []float64
+		batch  int
+		want   int
+	}{
+		{"empty", nil, 5, 0},
+		{"shorter than a batch", []float64{1, 2, 3}, 5, 0},
+		{"steady", steady, 5, 0},
+		{"transient", transient, 5, 20},
+	}
+	for _, tt := range tests {
+		if got := mser(tt.series, tt.batch); got != tt.want {
+			t.Errorf("%s: mser = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"fmt"
+	"goenv/Services"
+	"strings"
+	"time"
+)
+
+// Initializations
+
+// WashConfig is a struct for the configuration of the car wash
+type WashConfig struct {
+	Bays    int     `yaml:"bays" json:"bays"`   // no wash when zero
+	Share   float64 `yaml:"share" json:"share"` // share of cars buying a wash
+	TimeMin int     `yaml:"time_min" json:"time_min"`
+	TimeMax int     `yaml:"time_max" json:"time_max"`
+	Buffer  int     `yaml:"buffer" json:"buffer"` // cars waiting for a bay
+}
+
+// WashStats is a struct for output construction of the car wash
+type WashStats struct {
+	StationStats `yaml:",inline"`
+	Utilization  float64 `yaml:"utilization" json:"utilization"` // busy share of the bays
+}
+
+// Utilities
+
+// enabled reports whether the station has a car wash
+func (wash WashConfig) enabled() bool {
+	return wash.Bays > 0
+}
+
+// validate checks the wash bays and programs
+func (wash WashConfig) validate() error {
+	if wash.Bays < 0 || wash.Buffer < 0 {
+		return fmt.Errorf("wash.bays and wash.buffer must not be negative")
+	}
+	if wash.Share < 0 || wash.Share > 1 {
+		return fmt.Errorf("wash.share must be between 0 and 1")
+	}
+	if !wash.enabled() {
+		return nil
+	}
+	return checkRange("wash.time", wash.TimeMin, wash.TimeMax)
+}
+
+// setup converts the config to the station car wash
+func (wash WashConfig) setup() Services.WashSetup {
+	return Services.WashSetup{Bays: wash.Bays, Share: wash.Share, MinT: wash.TimeMin, MaxT: wash.TimeMax, Buffer: wash.Buffer}
+}
+
+// pipeline returns the stages of the config, the default one with a wash after payment when empty
+func (config *Config) pipeline() []Services.Stage {
+	if len(config.Pipeline) == 0 {
+		pipeline := append([]Services.Stage{}, Services.DefaultPipeline...)
+		if config.Wash.enabled() {
+			pipeline = append(pipeline, Services.StageWash)
+		}
+		return pipeline
+	}
+	var pipeline []Services.Stage
+	for _, name := range config.Pipeline {
+		stage, _ := Services.ParseStage(strings.TrimSpace(name))
+		pipeline = append(pipeline, stage)
+	}
+	return pipeline
+}
+
+// validatePipeline checks that cars fuel first, pay once and wash at most once
+func (config *Config) validatePipeline() error {
+	seen := make(map[Services.Stage]bool)
+	for i, name := range config.Pipeline {
+		stage, err := Services.ParseStage(strings.TrimSpace(name))
+		if err != nil {
+			return fmt.Errorf("pipeline: %v", err)
+		}
+		if seen[stage] {
+			return fmt.Errorf("pipeline lists %s twice", stage)
+		}
+		if (i == 0) != (stage == Services.StageFuel) {
+			return fmt.Errorf("pipeline must start with fuel")
+		}
+		seen[stage] = true
+	}
+	if len(config.Pipeline) > 0 && !seen[Services.StagePayment] {
+		return fmt.Errorf("pipeline must contain payment")
+	}
+	if len(config.Pipeline) > 0 && config.Wash.enabled() && !seen[Services.StageWash] {
+		return fmt.Errorf("wash.bays need a wash stage in the pipeline")
+	}
+	return nil
+}
+
+// washStats computes the queueing of the washed cars and the busy share of the bays
+func washStats(cars []*Services.Car, bays []Services.QueueState) *WashStats {
+	stats := &WashStats{}
+	var totalQueue time.Duration
+	var times []int
+	for _, car := range cars {
+		if !car.Wash {
+			continue
+		}
+		stats.TotalCars++
+		stats.TotalTime += int(car.WashTime)
+		totalQueue += car.WashQueueTime
+		stats.MaxQueueTime = max(stats.MaxQueueTime, int(car.WashQueueTime))
+		times = append(times, int(car.WashQueueTime))
+	}
+	if stats.TotalCars != 0 {
+		stats.AvgQueueTime = int(totalQueue) / stats.TotalCars
+		stats.P95QueueTime = percentile(times, 0.95)
+	}
+	for _, bay := range bays {
+		stats.Utilization += bay.Utilization / float64(len(bays))
+	}
+	return stats
+}
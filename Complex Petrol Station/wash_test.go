@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"goenv/Services"
+	"strings"
+	"testing"
+)
+
+func TestValidatePipeline(t *testing.T) {
+	tests := []struct {
+		name     string
+		pipeline []string
+		bays     int
+		invalid  string
+	}{
+		{"default", nil, 0, ""},
+		{"default with wash", nil, 2, ""},
+		{"wash before paying", []string{"fuel", "wash", "payment"}, 1, ""},
+		{"spaces", []string{"fuel", " payment "}, 0, ""},
+		{"unknown stage", []string{"fuel", "shop", "payment"}, 0, "pipeline: unknown stage"},
+		{"twice", []string{"fuel", "payment", "payment"}, 0, "lists payment twice"},
+		{"paying first", []string{"payment", "fuel"}, 0, "must start with fuel"},
+		{"fueling later", []string{"fuel", "payment", "fuel"}, 0, "lists fuel twice"},
+		{"no payment", []string{"fuel", "wash"}, 1, "must contain payment"},
+		{"bays without wash", []string{"fuel", "payment"}, 1, "need a wash stage"},
+	}
+	for _, tt := range tests {
+		config := defaultConfig()
+		config.Pipeline, config.Wash.Bays = tt.pipeline, tt.bays
+		err := config.validatePipeline()
+		if (err != nil) != (tt.invalid != "") || err != nil && !strings.Contains(err.Error(), tt.invalid) {
+			t.Errorf("%s: validatePipeline = %v, want error about %q", tt.name, err, tt.invalid)
+		}
+	}
+}
+
+func TestConfigPipeline(t *testing.T) {
+	tests := []struct {
+		name     string
+		pipeline []string
+		bays     int
+		want     string
+	}{
+		{"default", nil, 0, "[fuel payment]"},
+		{"default with wash", nil, 2, "[fuel payment wash]"},
+		{"listed", []string{"fuel", " wash", "payment"}, 1, "[fuel wash payment]"},
+	}
+	for _, tt := range tests {
+		config := defaultConfig()
+		config.Pipeline, config.Wash.Bays = tt.pipeline, tt.bays
+		if got := fmt.Sprint(config.pipeline()); got != tt.want {
+			t.Errorf("%s: pipeline = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+	if fmt.Sprint(Services.DefaultPipeline) != "[fuel payment]" {
+		t.Errorf("building the pipeline changed the default to %v", Services.DefaultPipeline)
+	}
+}
+
+func TestWashValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		wash    WashConfig
+		invalid string
+	}{
+		{"no wash", WashConfig{}, ""},
+		{"wash", WashConfig{Bays: 2, Share: 0.3, TimeMin: 10, TimeMax: 20, Buffer: 3}, ""},
+		{"negative bays", WashConfig{Bays: -1}, "wash.bays"},
+		{"share above 1", WashConfig{Bays: 1, Share: 1.2, TimeMin: 10, TimeMax: 20}, "wash.share"},
+		{"empty time range", WashConfig{Bays: 1, Share: 0.3, TimeMin: 20, TimeMax: 20}, "wash.time"},
+		{"times unused without bays", WashConfig{Share: 0.3}, ""},
+	}
+	for _, tt := range tests {
+		err := tt.wash.validate()
+		if (err != nil) != (tt.invalid != "") || err != nil && !strings.Contains(err.Error(), tt.invalid) {
+			t.Errorf("%s: validate = %v, want error about %q", tt.name, err, tt.invalid)
+		}
+	}
+}
+
+func TestWashStats(t *testing.T) {
+	cars := []*Services.Car{
+		{Wash: true, WashTime: 12, WashQueueTime: 0},
+		{Wash: false, WashQueueTime: 99},
+		{Wash: true, WashTime: 15, WashQueueTime: 6},
+	}
+	bays := []Services.QueueState{{Utilization: 0.5}, {Utilization: 0.25}}
+	stats := washStats(cars, bays)
+	want := WashStats{StationStats: StationStats{TotalCars: 2, TotalTime: 27, AvgQueueTime: 3, MaxQueueTime: 6, P95QueueTime: 6}, Utilization: 0.375}
+	if *stats != want {
+		t.Errorf("wash stats = %+v, want %+v", *stats, want)
+	}
+}
+
+func TestWashMetrics(t *testing.T) {
+	wash := &WashStats{StationStats: StationStats{TotalCars: 2, TotalTime: 27, AvgQueueTime: 3, MaxQueueTime: 6, P95QueueTime: 6}, Utilization: 0.375}
+	want := map[string]float64{
+		"wash.total_cars": 2, "wash.total_time": 27, "wash.avg_queue_time": 3,
+		"wash.max_queue_time": 6, "wash.p95_queue_time": 6, "wash.utilization": 0.375,
+	}
+	metrics := FinalStats{Wash: wash}.metrics()
+	for name, value := range want {
+		i := metricIndex(metrics, name)
+		if i < 0 || metrics[i].value != value {
+			t.Errorf("metric %s missing or not %v in %v", name, value, metrics)
+		}
+		if !isMetric(name) {
+			t.Errorf("isMetric(%q) = false", name)
+		}
+	}
+	if i := metricIndex(FinalStats{}.metrics(), "wash.total_cars"); i >= 0 {
+		t.Error("wash metrics without a wash")
+	}
+}